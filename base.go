@@ -17,6 +17,7 @@ package opentelemetry
 
 import (
 	"context"
+	"sync/atomic"
 
 	"github.com/tetratelabs/telemetry"
 	"go.opentelemetry.io/otel/attribute"
@@ -32,6 +33,12 @@ type baseMetric struct {
 	lvs  []tag.Mutator    // used when needing to append to context
 	set  attribute.Set    // precomputed if nothing is found in context
 	rest telemetry.Metric
+	// limiter is shared with all derived metrics, nil if unlimited
+	limiter *cardinalityLimiter
+	// enabled is shared with all derived metrics
+	enabled *atomic.Bool
+	// cache holds the metrics derived by With, nil for derived metrics
+	cache *labelCache
 }
 
 // Name returns the name value of a Metric.
@@ -48,7 +55,8 @@ func (f *baseMetric) Increment() {
 }
 
 // Decrement records a value of -1 for the current Metric.
-// For Sums, this is equivalent to subtracting -1 to the current value.
+// For Sums, this is equivalent to subtracting -1 to the current value. Only
+// Sums created with NewUpDownSum are allowed to decrease.
 // For Gauges, this is equivalent to setting the value to -1.
 // For Distributions, this is equivalent to making an observation of value -1.
 func (f *baseMetric) Decrement() {
@@ -75,6 +83,21 @@ func (f *baseMetric) toLabelValues(ctx context.Context) attribute.Set {
 }
 
 func (f *baseMetric) withLabelValues(lvs ...telemetry.LabelValue) ([]tag.Mutator, attribute.Set) {
+	ret, set, _ := f.resolveLabelValues(lvs...)
+	return ret, set
+}
+
+// labelSet returns the label set of the series identified by the label values
+// of the metric updated by lvs. It reports false if lvs can't be parsed.
+func (f *baseMetric) labelSet(lvs ...telemetry.LabelValue) (attribute.Set, bool) {
+	if len(lvs) == 0 {
+		return f.set, true
+	}
+	_, set, ok := f.resolveLabelValues(lvs...)
+	return set, ok
+}
+
+func (f *baseMetric) resolveLabelValues(lvs ...telemetry.LabelValue) ([]tag.Mutator, attribute.Set, bool) {
 	ret := make([]tag.Mutator, len(f.lvs)+len(lvs))
 	copy(ret, f.lvs)
 	for i := 0; i < len(lvs); i++ {
@@ -83,10 +106,10 @@ func (f *baseMetric) withLabelValues(lvs ...telemetry.LabelValue) ([]tag.Mutator
 	ctx, err := tag.New(context.Background(), ret...)
 	if err != nil {
 		f.ms.logger.Error("unable to parse LabelValues", err, "metric", f.name)
-		return ret, attribute.NewSet()
+		return ret, attribute.NewSet(), false
 	}
 
-	return ret, f.tagsToAttributeSet(tag.FromContext(ctx))
+	return ret, f.tagsToAttributeSet(tag.FromContext(ctx)), true
 }
 
 func (f *baseMetric) tagsToAttributeSet(tm *tag.Map) attribute.Set {
@@ -96,14 +119,26 @@ func (f *baseMetric) tagsToAttributeSet(tm *tag.Map) attribute.Set {
 	}
 	if f.ms.strictDimensions {
 		for k := range f.keys {
-			if val, ok := tm.Value(k); ok {
-				kvs = append(kvs, attribute.String(k.Name(), val))
+			if t, ok := tm.Tag(k); ok {
+				kvs = append(kvs, tagToAttribute(t))
 			}
 		}
 	} else {
 		tm.Iterate(func(t tag.Tag) {
-			kvs = append(kvs, attribute.String(t.Key.Name(), t.Value))
+			kvs = append(kvs, tagToAttribute(t))
 		})
 	}
 	return attribute.NewSet(kvs...)
 }
+
+// tagToAttribute returns the attribute holding the typed value of the tag.
+func tagToAttribute(t tag.Tag) attribute.KeyValue {
+	switch t.Type {
+	case tag.ValueTypeInt64:
+		return attribute.Int64(t.Key.Name(), t.Int64)
+	case tag.ValueTypeBool:
+		return attribute.Bool(t.Key.Name(), t.Bool)
+	default:
+		return attribute.String(t.Key.Name(), t.Value)
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright (c) Tetrate, Inc 2023.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opentelemetry
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	api "go.opentelemetry.io/otel/metric"
+)
+
+// overflowSet is the label set of the series receiving all recordings for
+// label sets beyond a metric's cardinality limit.
+var overflowSet = attribute.NewSet(attribute.Bool("otel.metric.overflow", true))
+
+// overflowMetricName is the name of the counter tracking the recordings routed
+// into overflow series.
+const overflowMetricName = "otel_metric_overflow_total"
+
+// cardinalityLimiter tracks the label sets recorded by a metric and all of its
+// derived (With) metrics.
+type cardinalityLimiter struct {
+	ms    *metricSink
+	name  string
+	limit int
+	// budget is shared with the other metrics of the sink, nil if the sink has
+	// no cardinality limit.
+	budget *cardinalityBudget
+
+	mu   sync.RWMutex
+	seen map[attribute.Set]struct{}
+}
+
+// newCardinalityLimiter returns a limiter for the named metric, or nil if
+// neither the metric nor the sink has a cardinality limit.
+func (m *metricSink) newCardinalityLimiter(name string, limit int) *cardinalityLimiter {
+	if limit <= 0 && m.cardinalityBudget == nil {
+		return nil
+	}
+	m.overflowCounter()
+	return &cardinalityLimiter{
+		ms:     m,
+		name:   name,
+		limit:  limit,
+		budget: m.cardinalityBudget,
+		seen:   map[attribute.Set]struct{}{},
+	}
+}
+
+// admit returns the label set to record with. This is the provided set if it
+// is known or the limit has not been reached yet, and overflowSet otherwise.
+func (c *cardinalityLimiter) admit(set attribute.Set) attribute.Set {
+	if c == nil || set.Len() == 0 {
+		return set
+	}
+	c.mu.RLock()
+	_, ok := c.seen[set]
+	c.mu.RUnlock()
+	if ok {
+		return set
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok = c.seen[set]; ok {
+		return set
+	}
+	if (c.limit <= 0 || len(c.seen) < c.limit) && c.budget.reserve() {
+		c.seen[set] = struct{}{}
+		return set
+	}
+	c.ms.recordOverflow(c.name)
+	return overflowSet
+}
+
+// forget releases the slot of the label set, so another label set can be
+// admitted in its place. It is called when a series is deleted.
+func (c *cardinalityLimiter) forget(set attribute.Set) {
+	if c == nil || set.Len() == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.seen[set]; ok {
+		delete(c.seen, set)
+		c.budget.release()
+	}
+}
+
+// cardinalityBudget holds the number of label sets recorded by all metrics of
+// a sink. It is shared by a sink and its namespaced children.
+type cardinalityBudget struct {
+	limit int
+
+	mu sync.Mutex
+	n  int
+}
+
+// reserve takes a slot from the budget, and returns false if none is left.
+func (b *cardinalityBudget) reserve() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.n >= b.limit {
+		return false
+	}
+	b.n++
+	return true
+}
+
+// release returns a slot to the budget.
+func (b *cardinalityBudget) release() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	b.n--
+	b.mu.Unlock()
+}
+
+// overflowCounter lazily creates the counter tracking the recordings routed
+// into overflow series. It is shared by a sink and its namespaced children.
+type overflowCounter struct {
+	once    sync.Once
+	c       api.Float64Counter
+	enabled *atomic.Bool
+}
+
+// overflowCounter registers the counter tracking the recordings routed into
+// overflow series, so it can be toggled with SetEnabled before the first
+// overflow, and returns it.
+func (m *metricSink) overflowCounter() *overflowCounter {
+	o := m.overflow
+	o.once.Do(func() {
+		o.enabled, _ = m.knownMetrics.register(MetricDefinition{
+			Name:        overflowMetricName,
+			Type:        "Sum",
+			Description: "Number of recordings routed into the overflow series, by metric",
+		}, true, false)
+		c, err := m.meter.Float64Counter(overflowMetricName,
+			api.WithDescription("Number of recordings routed into the overflow series, by metric"))
+		if err != nil {
+			m.logger.Error("failed to create overflow counter", err)
+			return
+		}
+		o.c = c
+	})
+	return o
+}
+
+// recordOverflow counts a recording of the named metric which was routed into
+// its overflow series, unless the counter is disabled.
+func (m *metricSink) recordOverflow(name string) {
+	o := m.overflowCounter()
+	if o.c != nil && o.enabled.Load() {
+		o.c.Add(context.Background(), 1, api.WithAttributes(attribute.String("metric", name)))
+	}
+}
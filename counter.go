@@ -26,15 +26,29 @@ import (
 
 type counter struct {
 	baseMetric
-	c api.Float64Counter
+	c adder
+	// opts records with the bound label set
+	opts []api.AddOption
 }
 
 var _ telemetry.Metric = (*counter)(nil)
 
-func (m *metricSink) newCounter(name, description string, o telemetry.MetricOptions) *counter {
-	c, err := m.meter.Float64Counter(name,
-		api.WithDescription(description),
-		api.WithUnit(string(o.Unit)))
+func (m *metricSink) newCounter(name, description string, o metricOptions) *counter {
+	var (
+		c   adder
+		err error
+	)
+	if o.isInt64() {
+		var ic api.Int64Counter
+		ic, err = m.meter.Int64Counter(name,
+			api.WithDescription(description),
+			api.WithUnit(string(o.Unit)))
+		c = int64Adder{c: ic}
+	} else {
+		c, err = m.meter.Float64Counter(name,
+			api.WithDescription(description),
+			api.WithUnit(string(o.Unit)))
+	}
 	if err != nil {
 		log.Error("failed to create counter", err)
 	}
@@ -46,10 +60,13 @@ func (m *metricSink) newCounter(name, description string, o telemetry.MetricOpti
 		}
 	}
 	r.baseMetric = baseMetric{
-		ms:   m,
-		name: name,
-		rest: r,
-		keys: keysMap,
+		ms:      m,
+		name:    name,
+		rest:    r,
+		keys:    keysMap,
+		limiter: m.newCardinalityLimiter(name, o.cardinalityLimit),
+		enabled: o.enabled,
+		cache:   newLabelCache(m.labelCacheSize),
 	}
 	return r
 }
@@ -59,14 +76,28 @@ func (f *counter) Record(value float64) {
 }
 
 func (f *counter) RecordContext(ctx context.Context, value float64) {
-	if set := f.baseMetric.toLabelValues(ctx); set.Len() > 0 {
-		f.c.Add(ctx, value, api.WithAttributeSet(set))
+	if !f.enabled.Load() {
+		return
+	}
+	set := f.baseMetric.toLabelValues(ctx)
+	if set.Len() > 0 {
+		if as := f.limiter.admit(set); f.opts != nil && as.Equals(&f.set) {
+			// recording with the bound label set, reuse its options.
+			f.c.Add(ctx, value, f.opts...)
+		} else {
+			f.c.Add(ctx, value, api.WithAttributeSet(as))
+		}
 	} else {
 		f.c.Add(ctx, value)
 	}
+	f.ms.recordHooks.onRecord(f.name, set, value)
 }
 
 func (f *counter) With(labelValues ...telemetry.LabelValue) telemetry.Metric {
+	key, cached, cacheable := f.cache.lookup(labelValues)
+	if cached != nil {
+		return cached
+	}
 	nm := &counter{
 		c: f.c,
 	}
@@ -75,11 +106,19 @@ func (f *counter) With(labelValues ...telemetry.LabelValue) telemetry.Metric {
 		keysMap[k] = true
 	}
 	nm.baseMetric = baseMetric{
-		ms:   f.ms,
-		name: f.name,
-		rest: nm,
-		keys: keysMap,
+		ms:      f.ms,
+		name:    f.name,
+		rest:    nm,
+		keys:    keysMap,
+		limiter: f.limiter,
+		enabled: f.enabled,
 	}
 	nm.lvs, nm.set = f.baseMetric.withLabelValues(labelValues...)
+	if nm.set.Len() > 0 {
+		nm.opts = []api.AddOption{api.WithAttributeSet(nm.set)}
+	}
+	if cacheable {
+		f.cache.store(key, nm)
+	}
 	return nm
 }
@@ -0,0 +1,51 @@
+// Copyright (c) Tetrate, Inc 2023.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opentelemetry
+
+import (
+	"github.com/tetratelabs/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Deleter is implemented by the gauges of this package, which keep exporting
+// the last recorded value of every label series until it is deleted.
+//
+//	if d, ok := connections.With(pod.Insert(name)).(opentelemetry.Deleter); ok {
+//		d.Delete()
+//	}
+type Deleter interface {
+	// Delete removes the series with exactly the label values the metric was
+	// created with, updated by the provided label values. It reports whether a
+	// series was removed.
+	Delete(labelValues ...telemetry.LabelValue) bool
+
+	// DeletePartialMatch removes all series which carry the label values the
+	// metric was created with, updated by the provided label values. It
+	// returns the number of removed series.
+	DeletePartialMatch(labelValues ...telemetry.LabelValue) int
+}
+
+// containsAll reports whether set holds all attributes found in subset.
+func containsAll(set, subset attribute.Set) bool {
+	iter := subset.Iter()
+	for iter.Next() {
+		kv := iter.Attribute()
+		v, ok := set.Value(kv.Key)
+		if !ok || v != kv.Value {
+			return false
+		}
+	}
+	return true
+}
@@ -30,56 +30,115 @@ type derivedGauge struct {
 	mu    sync.RWMutex
 	attrs map[attribute.Set]func() float64
 
-	name string
+	base baseMetric
 }
 
-var _ telemetry.DerivedMetric = (*derivedGauge)(nil)
+var (
+	_ telemetry.DerivedMetric = (*derivedGauge)(nil)
+	_ Deleter                 = (*derivedGauge)(nil)
+)
 
-func (m *metricSink) newDerivedGauge(name, description string) telemetry.DerivedMetric {
+func (m *metricSink) newDerivedGauge(name, description string, o metricOptions) telemetry.DerivedMetric {
 	dm := &derivedGauge{
-		name:  name,
 		attrs: map[attribute.Set]func() float64{},
 	}
-	_, err := m.meter.Float64ObservableGauge(name,
-		api.WithDescription(description),
-		api.WithFloat64Callback(func(ctx context.Context, observer api.Float64Observer) error {
-			dm.mu.RLock()
-			defer dm.mu.RUnlock()
-			for kv, compute := range dm.attrs {
-				observer.Observe(compute(), api.WithAttributeSet(kv))
-			}
-			return nil
-		}))
+	var err error
+	if o.isInt64() {
+		_, err = m.meter.Int64ObservableGauge(name,
+			api.WithDescription(description),
+			api.WithUnit(string(o.Unit)),
+			api.WithInt64Callback(func(ctx context.Context, observer api.Int64Observer) error {
+				dm.observe(func(value float64, kv attribute.Set) {
+					observer.Observe(toInt64(value), api.WithAttributeSet(kv))
+				})
+				return nil
+			}))
+	} else {
+		_, err = m.meter.Float64ObservableGauge(name,
+			api.WithDescription(description),
+			api.WithUnit(string(o.Unit)),
+			api.WithFloat64Callback(func(ctx context.Context, observer api.Float64Observer) error {
+				dm.observe(func(value float64, kv attribute.Set) {
+					observer.Observe(value, api.WithAttributeSet(kv))
+				})
+				return nil
+			}))
+	}
 	if err != nil {
-		log.Error("failed to create derived gauge", err)
+		m.logger.Error("failed to create derived gauge", err)
+	}
+	keysMap := map[tag.Key]bool{}
+	for _, k := range o.Labels {
+		if l, ok := k.(*labelImpl); ok {
+			keysMap[l.label] = true
+		}
+	}
+	dm.base = baseMetric{
+		ms:      m,
+		name:    name,
+		keys:    keysMap,
+		enabled: o.enabled,
 	}
 	return dm
 }
 
+// observe calls fn with the current value of all series of the derived gauge,
+// unless it is disabled.
+func (d *derivedGauge) observe(fn func(value float64, kv attribute.Set)) {
+	if !d.base.enabled.Load() {
+		return
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for kv, compute := range d.attrs {
+		fn(compute(), kv)
+	}
+}
+
 func (d *derivedGauge) Name() string {
-	return d.name
+	return d.base.name
 }
 
+// ValueFrom registers valueFn to be called on collection to get the value of
+// the series identified by the provided label values. Registering a function
+// for a known series replaces the previous one. Use Delete to unregister it.
 func (d *derivedGauge) ValueFrom(valueFn func() float64, labelValues ...telemetry.LabelValue) telemetry.DerivedMetric {
+	_, as := d.base.withLabelValues(labelValues...)
 	d.mu.Lock()
 	defer d.mu.Unlock()
-	m := make([]tag.Mutator, len(labelValues))
-	for i := 0; i < len(labelValues); i++ {
-		m[i] = labelValues[i].(tag.Mutator)
-	}
-	ctx, err := tag.New(context.Background(), m...)
-	if err != nil {
-		log.Error("unable to parse LabelValues", err, "metric", d.name)
-		as := attribute.NewSet()
-		d.attrs[as] = valueFn
-		return d
-	}
-	tm := tag.FromContext(ctx)
-	lv := make([]attribute.KeyValue, tm.Len())
-	tm.Iterate(func(t tag.Tag) {
-		lv = append(lv, attribute.String(t.Key.Name(), t.Value))
-	})
-	as := attribute.NewSet(lv...)
 	d.attrs[as] = valueFn
 	return d
 }
+
+// Delete implements Deleter.
+func (d *derivedGauge) Delete(labelValues ...telemetry.LabelValue) bool {
+	_, as, ok := d.base.resolveLabelValues(labelValues...)
+	if !ok {
+		return false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.attrs[as]; !ok {
+		return false
+	}
+	delete(d.attrs, as)
+	return true
+}
+
+// DeletePartialMatch implements Deleter.
+func (d *derivedGauge) DeletePartialMatch(labelValues ...telemetry.LabelValue) int {
+	_, as, ok := d.base.resolveLabelValues(labelValues...)
+	if !ok {
+		return 0
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var deleted int
+	for kv := range d.attrs {
+		if containsAll(kv, as) {
+			delete(d.attrs, kv)
+			deleted++
+		}
+	}
+	return deleted
+}
@@ -27,19 +27,40 @@ import (
 
 type distribution struct {
 	baseMetric
-	d api.Float64Histogram
+	d    recorder
+	unit telemetry.Unit
+	// opts records with the bound label set
+	opts []api.RecordOption
 }
 
 var _ telemetry.Metric = (*distribution)(nil)
 
-func (m *metricSink) newDistribution(name, description string, o telemetry.MetricOptions) *distribution {
-	d, err := m.meter.Float64Histogram(name,
-		api.WithDescription(description),
-		api.WithUnit(string(o.Unit)))
+func (m *metricSink) newDistribution(name, description string, bounds []float64, o metricOptions) *distribution {
+	var (
+		d   recorder
+		err error
+	)
+	if o.isInt64() {
+		if isCoarseDurationUnit(o.Unit) {
+			m.logger.Error("integer distribution truncates durations", errCoarseDurationUnit,
+				"metric", name, "unit", o.Unit)
+		}
+		var h api.Int64Histogram
+		h, err = m.meter.Int64Histogram(name,
+			api.WithDescription(description),
+			api.WithUnit(string(o.Unit)),
+			api.WithExplicitBucketBoundaries(bounds...))
+		d = int64Recorder{h: h}
+	} else {
+		d, err = m.meter.Float64Histogram(name,
+			api.WithDescription(description),
+			api.WithUnit(string(o.Unit)),
+			api.WithExplicitBucketBoundaries(bounds...))
+	}
 	if err != nil {
 		log.Error("failed to create distribution", err)
 	}
-	r := &distribution{d: d}
+	r := &distribution{d: d, unit: o.Unit}
 	keysMap := map[tag.Key]bool{}
 	for _, k := range o.Labels {
 		if l, ok := k.(*labelImpl); ok {
@@ -47,11 +68,14 @@ func (m *metricSink) newDistribution(name, description string, o telemetry.Metri
 		}
 	}
 	r.baseMetric = baseMetric{
-		ms:   m,
-		name: name,
-		rest: r,
-		keys: keysMap,
-		set:  attribute.NewSet(),
+		ms:      m,
+		name:    name,
+		rest:    r,
+		keys:    keysMap,
+		limiter: m.newCardinalityLimiter(name, o.cardinalityLimit),
+		enabled: o.enabled,
+		cache:   newLabelCache(m.labelCacheSize),
+		set:     attribute.NewSet(),
 	}
 	return r
 }
@@ -61,16 +85,31 @@ func (f *distribution) Record(value float64) {
 }
 
 func (f *distribution) RecordContext(ctx context.Context, value float64) {
-	if set := f.baseMetric.toLabelValues(ctx); set.Len() > 0 {
-		f.d.Record(ctx, value, api.WithAttributeSet(set))
+	if !f.enabled.Load() {
+		return
+	}
+	set := f.baseMetric.toLabelValues(ctx)
+	if set.Len() > 0 {
+		if as := f.limiter.admit(set); f.opts != nil && as.Equals(&f.set) {
+			// recording with the bound label set, reuse its options.
+			f.d.Record(ctx, value, f.opts...)
+		} else {
+			f.d.Record(ctx, value, api.WithAttributeSet(as))
+		}
 	} else {
 		f.d.Record(ctx, value)
 	}
+	f.ms.recordHooks.onRecord(f.name, set, value)
 }
 
 func (f *distribution) With(labelValues ...telemetry.LabelValue) telemetry.Metric {
+	key, cached, cacheable := f.cache.lookup(labelValues)
+	if cached != nil {
+		return cached
+	}
 	nm := &distribution{
-		d: f.d,
+		d:    f.d,
+		unit: f.unit,
 	}
 	keysMap := make(map[tag.Key]bool)
 	for k := range f.keys {
@@ -78,11 +117,25 @@ func (f *distribution) With(labelValues ...telemetry.LabelValue) telemetry.Metri
 	}
 
 	nm.baseMetric = baseMetric{
-		ms:   f.ms,
-		name: f.name,
-		rest: nm,
-		keys: keysMap,
+		ms:      f.ms,
+		name:    f.name,
+		rest:    nm,
+		keys:    keysMap,
+		limiter: f.limiter,
+		enabled: f.enabled,
 	}
 	nm.lvs, nm.set = f.baseMetric.withLabelValues(labelValues...)
+	if nm.set.Len() > 0 {
+		nm.opts = []api.RecordOption{api.WithAttributeSet(nm.set)}
+	}
+	if cacheable {
+		f.cache.store(key, nm)
+	}
 	return nm
 }
+
+// durationUnit returns the unit used by NewTimer and Since to record elapsed
+// time.
+func (f *distribution) durationUnit() telemetry.Unit {
+	return f.unit
+}
@@ -0,0 +1,42 @@
+// Copyright (c) Tetrate, Inc 2023.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opentelemetry_test
+
+import (
+	"context"
+
+	"github.com/tetratelabs/telemetry"
+
+	"github.com/tetratelabs/telemetry-opentelemetry"
+)
+
+var requestDuration telemetry.Metric
+
+func ExampleNewTimer() {
+	telemetry.ToGlobalMetricSink(func(m telemetry.MetricSink) {
+		requestDuration = m.NewDistribution(
+			"request_duration_milliseconds",
+			"Distribution of request durations",
+			[]float64{10, 100, 1000},
+			telemetry.WithUnit(telemetry.Milliseconds),
+		)
+	})
+
+	telemetry.SetGlobalMetricSink(opentelemetry.New("example"))
+
+	timer := opentelemetry.NewTimer(requestDuration)
+	// handle the request
+	timer.StopContext(context.Background())
+}
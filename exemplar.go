@@ -0,0 +1,59 @@
+// Copyright (c) Tetrate, Inc 2023.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opentelemetry
+
+import (
+	"go.opentelemetry.io/otel/sdk/metric/exemplar"
+)
+
+// ExemplarFilter decides which recordings are offered as exemplars. Exemplars
+// link Sum and Distribution data points to the trace and span active in the
+// context passed to RecordContext.
+type ExemplarFilter int
+
+const (
+	// ExemplarFilterDefault leaves the choice to the OpenTelemetry SDK, which
+	// honours the OTEL_METRICS_EXEMPLAR_FILTER environment variable and falls
+	// back to ExemplarFilterTraceSampled.
+	ExemplarFilterDefault ExemplarFilter = iota
+	// ExemplarFilterTraceSampled offers recordings made within a sampled span.
+	ExemplarFilterTraceSampled
+	// ExemplarFilterAlways offers all recordings.
+	ExemplarFilterAlways
+	// ExemplarFilterNever disables exemplars.
+	ExemplarFilterNever
+)
+
+func (f ExemplarFilter) toSDK() exemplar.Filter {
+	switch f {
+	case ExemplarFilterTraceSampled:
+		return exemplar.TraceBasedFilter
+	case ExemplarFilterAlways:
+		return exemplar.AlwaysOnFilter
+	case ExemplarFilterNever:
+		return exemplar.AlwaysOffFilter
+	default:
+		return nil
+	}
+}
+
+// WithExemplarFilter sets the filter deciding which recordings are attached
+// as exemplars to exported data points. The filter is applied by exporters
+// through Start.
+func WithExemplarFilter(f ExemplarFilter) SinkOption {
+	return func(ms *metricSink) {
+		ms.exemplarFilter = f
+	}
+}
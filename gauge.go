@@ -17,7 +17,9 @@ package opentelemetry
 
 import (
 	"context"
+	"math"
 	"sync"
+	"sync/atomic"
 
 	"github.com/tetratelabs/telemetry"
 	"go.opentelemetry.io/otel/attribute"
@@ -28,35 +30,51 @@ import (
 
 type gauge struct {
 	baseMetric
-	g api.Float64ObservableGauge
+	g api.Observable
 
-	// attributeSets stores a map of attributes -> values, for gauges.
-	attributeSetsMutex *sync.RWMutex
-	attributeSets      map[attribute.Set]*gaugeValues
-	currentGaugeSet    *gaugeValues
+	// attributeSets stores a map of attributes -> *gaugeValues, for gauges.
+	// Series are added and observed without locking; values are stored in
+	// atomic cells.
+	attributeSets   *sync.Map
+	currentGaugeSet *gaugeValues
 }
 
-var _ telemetry.Metric = (*gauge)(nil)
+var (
+	_ telemetry.Metric = (*gauge)(nil)
+	_ Deleter          = (*gauge)(nil)
+)
 
-func (m *metricSink) newGauge(name, description string, o telemetry.MetricOptions) *gauge {
+func (m *metricSink) newGauge(name, description string, o metricOptions) *gauge {
 	r := &gauge{
-		attributeSetsMutex: &sync.RWMutex{},
-		currentGaugeSet:    &gaugeValues{},
-	}
-	r.attributeSets = map[attribute.Set]*gaugeValues{
-		attribute.NewSet(): r.currentGaugeSet,
-	}
-	g, err := m.meter.Float64ObservableGauge(name,
-		api.WithFloat64Callback(func(ctx context.Context, observer api.Float64Observer) error {
-			r.attributeSetsMutex.Lock()
-			defer r.attributeSetsMutex.Unlock()
-			for _, gv := range r.attributeSets {
-				observer.Observe(gv.val, gv.opt...)
-			}
-			return nil
-		}),
-		api.WithDescription(description),
-		api.WithUnit(string(o.Unit)))
+		attributeSets:   &sync.Map{},
+		currentGaugeSet: &gaugeValues{},
+	}
+	r.attributeSets.Store(attribute.NewSet(), r.currentGaugeSet)
+	var (
+		g   api.Observable
+		err error
+	)
+	if o.isInt64() {
+		g, err = m.meter.Int64ObservableGauge(name,
+			api.WithInt64Callback(func(ctx context.Context, observer api.Int64Observer) error {
+				r.observe(func(gv *gaugeValues) {
+					observer.Observe(toInt64(gv.load()), gv.opt...)
+				})
+				return nil
+			}),
+			api.WithDescription(description),
+			api.WithUnit(string(o.Unit)))
+	} else {
+		g, err = m.meter.Float64ObservableGauge(name,
+			api.WithFloat64Callback(func(ctx context.Context, observer api.Float64Observer) error {
+				r.observe(func(gv *gaugeValues) {
+					observer.Observe(gv.load(), gv.opt...)
+				})
+				return nil
+			}),
+			api.WithDescription(description),
+			api.WithUnit(string(o.Unit)))
+	}
 	if err != nil {
 		m.logger.Error("failed to create gauge", err)
 	}
@@ -68,55 +86,164 @@ func (m *metricSink) newGauge(name, description string, o telemetry.MetricOption
 		}
 	}
 	r.baseMetric = baseMetric{
-		ms:   m,
-		name: name,
-		rest: r,
-		keys: keysMap,
-		set:  attribute.NewSet(),
+		ms:      m,
+		name:    name,
+		rest:    r,
+		keys:    keysMap,
+		limiter: m.newCardinalityLimiter(name, o.cardinalityLimit),
+		enabled: o.enabled,
+		cache:   newLabelCache(m.labelCacheSize),
+		set:     attribute.NewSet(),
 	}
 	return r
 }
 
+// observe calls fn with all series of the gauge, unless it is disabled.
+func (f *gauge) observe(fn func(gv *gaugeValues)) {
+	if !f.enabled.Load() {
+		return
+	}
+	f.attributeSets.Range(func(_, gv any) bool {
+		fn(gv.(*gaugeValues))
+		return true
+	})
+}
+
 func (f *gauge) Record(value float64) {
 	f.RecordContext(context.Background(), value)
 }
 
 func (f *gauge) RecordContext(ctx context.Context, value float64) {
+	if !f.enabled.Load() {
+		return
+	}
 	// TODO: https://github.com/open-telemetry/opentelemetry-specification/issues/2318 use synchronous gauge so we don't need to deal with this
-	f.attributeSetsMutex.Lock()
-	f.currentGaugeSet.val = value
-	f.attributeSetsMutex.Unlock()
+	gv, set := f.currentGaugeSet, f.set
+	if ctx != context.Background() {
+		// labels found in context select the series to record to.
+		if s := f.baseMetric.toLabelValues(ctx); !s.Equals(&set) {
+			gv, set = nil, s
+		}
+	}
+	// admit on each recording, so a series created over the limit gets its
+	// own once a slot is released.
+	if as := f.limiter.admit(set); gv == nil || !as.Equals(&set) {
+		gv, set = f.series(as), as
+	}
+	gv.store(value)
+	if gv.deleted.Load() {
+		// re-add our series as it was deleted.
+		f.restore(set, gv, value)
+	}
+	f.ms.recordHooks.onRecord(f.name, set, value)
 }
 
 func (f *gauge) With(labelValues ...telemetry.LabelValue) telemetry.Metric {
+	key, cached, cacheable := f.cache.lookup(labelValues)
+	if cached != nil {
+		return cached
+	}
 	nm := &gauge{
-		g:                  f.g,
-		attributeSetsMutex: f.attributeSetsMutex,
-		attributeSets:      f.attributeSets,
+		g:             f.g,
+		attributeSets: f.attributeSets,
 	}
 	lvs, set := f.baseMetric.withLabelValues(labelValues...)
-	if _, f := nm.attributeSets[set]; !f {
-		nm.attributeSets[set] = &gaugeValues{
-			opt: []api.ObserveOption{api.WithAttributeSet(set)},
-		}
+	if as := f.limiter.admit(set); as.Equals(&set) {
+		// over the limit, the series is looked up on each recording instead.
+		nm.currentGaugeSet = f.series(set)
 	}
-	nm.currentGaugeSet = nm.attributeSets[set]
 	keysMap := make(map[tag.Key]bool)
 	for k := range f.keys {
 		keysMap[k] = true
 	}
 	nm.baseMetric = baseMetric{
-		ms:   f.ms,
-		name: f.name,
-		keys: keysMap,
-		lvs:  lvs,
-		rest: nm,
-		set:  set,
+		ms:      f.ms,
+		name:    f.name,
+		keys:    keysMap,
+		lvs:     lvs,
+		rest:    nm,
+		set:     set,
+		limiter: f.limiter,
+		enabled: f.enabled,
+	}
+	if cacheable {
+		f.cache.store(key, nm)
 	}
 	return nm
 }
 
+// series returns the cell of the series identified by set, adding it if
+// unknown.
+func (f *gauge) series(set attribute.Set) *gaugeValues {
+	if gv, ok := f.attributeSets.Load(set); ok {
+		return gv.(*gaugeValues)
+	}
+	gv, _ := f.attributeSets.LoadOrStore(set, &gaugeValues{
+		opt: []api.ObserveOption{api.WithAttributeSet(set)},
+	})
+	return gv.(*gaugeValues)
+}
+
+// restore re-adds the deleted cell gv of the series identified by set. If the
+// series was added again in the meantime, value is recorded to its new cell.
+func (f *gauge) restore(set attribute.Set, gv *gaugeValues, value float64) {
+	if !gv.deleted.CompareAndSwap(true, false) {
+		return
+	}
+	if actual, loaded := f.attributeSets.LoadOrStore(set, gv); loaded {
+		actual.(*gaugeValues).store(value)
+	}
+}
+
+// Delete implements Deleter.
+func (f *gauge) Delete(labelValues ...telemetry.LabelValue) bool {
+	set, ok := f.baseMetric.labelSet(labelValues...)
+	if !ok {
+		return false
+	}
+	gv, ok := f.attributeSets.LoadAndDelete(set)
+	if ok {
+		gv.(*gaugeValues).deleted.Store(true)
+		f.limiter.forget(set)
+	}
+	return ok
+}
+
+// DeletePartialMatch implements Deleter.
+func (f *gauge) DeletePartialMatch(labelValues ...telemetry.LabelValue) int {
+	set, ok := f.baseMetric.labelSet(labelValues...)
+	if !ok {
+		return 0
+	}
+	var deleted int
+	f.attributeSets.Range(func(as, _ any) bool {
+		if !containsAll(as.(attribute.Set), set) {
+			return true
+		}
+		if gv, ok := f.attributeSets.LoadAndDelete(as); ok {
+			gv.(*gaugeValues).deleted.Store(true)
+			f.limiter.forget(as.(attribute.Set))
+			deleted++
+		}
+		return true
+	})
+	return deleted
+}
+
+// gaugeValues is the lock-free cell holding the last value of a series.
 type gaugeValues struct {
-	val float64
-	opt []api.ObserveOption
+	// val holds the bits of the float64 value.
+	val atomic.Uint64
+	// deleted is set when the series has been deleted, so the next recording
+	// adds it again.
+	deleted atomic.Bool
+	opt     []api.ObserveOption
+}
+
+func (gv *gaugeValues) store(value float64) {
+	gv.val.Store(math.Float64bits(value))
+}
+
+func (gv *gaugeValues) load() float64 {
+	return math.Float64frombits(gv.val.Load())
 }
@@ -0,0 +1,62 @@
+// Copyright (c) Tetrate, Inc 2023.
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opentelemetry
+
+import (
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// RecordHook has a callback function which is invoked when a metric is
+// recorded.
+type RecordHook interface {
+	// OnRecord is called with the name of the recorded metric, the label set
+	// the value was recorded with and the recorded value.
+	OnRecord(name string, labels attribute.Set, value float64)
+}
+
+// RegisterRecordHook adds a RecordHook for the metric with the provided name.
+// Hooks are invoked after the value has been recorded by the metric.
+func (m *metricSink) RegisterRecordHook(name string, hook RecordHook) {
+	m.recordHooks.register(m.fullName(name), hook)
+}
+
+// recordHooks stores the record hooks by metric name.
+type recordHooks struct {
+	mu    sync.RWMutex
+	hooks map[string][]RecordHook
+}
+
+func (r *recordHooks) register(name string, hook RecordHook) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.hooks == nil {
+		r.hooks = map[string][]RecordHook{}
+	}
+	r.hooks[name] = append(r.hooks[name], hook)
+}
+
+func (r *recordHooks) onRecord(name string, set attribute.Set, value float64) {
+	r.mu.RLock()
+	hooks := r.hooks[name]
+	r.mu.RUnlock()
+	// hooks are called without holding the lock as they are allowed to record
+	// other metrics.
+	for _, hook := range hooks {
+		hook.OnRecord(name, set, value)
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright (c) Tetrate, Inc 2023.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opentelemetry
+
+import (
+	"context"
+	"math"
+
+	"github.com/tetratelabs/telemetry"
+	api "go.opentelemetry.io/otel/metric"
+)
+
+// ValueType selects the type of the values a metric exports.
+type ValueType int
+
+const (
+	// ValueTypeAuto exports integer values for metrics declared with
+	// telemetry.WithUnit(telemetry.Bytes), and floating point values
+	// otherwise.
+	ValueTypeAuto ValueType = iota
+	// ValueTypeFloat64 exports floating point values.
+	ValueTypeFloat64
+	// ValueTypeInt64 exports integer values. Recorded values are rounded to
+	// the nearest integer.
+	ValueTypeInt64
+)
+
+// WithValueType sets the type of the values the metric exports. Integer
+// metrics do not lose precision past 2^53 once accumulated, and are exported
+// as integer data points. Defaults to ValueTypeAuto.
+func WithValueType(t ValueType) telemetry.MetricOption {
+	return extendOption(func(o *metricOptions) {
+		o.valueType = t
+	})
+}
+
+// isInt64 reports whether the metric is backed by an int64 instrument.
+func (o metricOptions) isInt64() bool {
+	switch o.valueType {
+	case ValueTypeInt64:
+		return true
+	case ValueTypeAuto:
+		return o.Unit == telemetry.Bytes
+	default:
+		return false
+	}
+}
+
+// toInt64 rounds value to the nearest integer.
+func toInt64(value float64) int64 {
+	return int64(math.Round(value))
+}
+
+// adder is implemented by the float64 counters, and by int64Adder for their
+// int64 counterparts.
+type adder interface {
+	Add(ctx context.Context, value float64, opts ...api.AddOption)
+}
+
+// int64Adder adapts an int64 counter to adder.
+type int64Adder struct {
+	c interface {
+		Add(ctx context.Context, value int64, opts ...api.AddOption)
+	}
+}
+
+func (a int64Adder) Add(ctx context.Context, value float64, opts ...api.AddOption) {
+	a.c.Add(ctx, toInt64(value), opts...)
+}
+
+// recorder is implemented by api.Float64Histogram, and by int64Recorder for
+// api.Int64Histogram.
+type recorder interface {
+	Record(ctx context.Context, value float64, opts ...api.RecordOption)
+}
+
+// int64Recorder adapts an api.Int64Histogram to recorder.
+type int64Recorder struct {
+	h api.Int64Histogram
+}
+
+func (r int64Recorder) Record(ctx context.Context, value float64, opts ...api.RecordOption) {
+	r.h.Record(ctx, toInt64(value), opts...)
+}
@@ -23,9 +23,8 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil/promlint"
 	dto "github.com/prometheus/client_model/go"
-	"github.com/tetratelabs/telemetry"
-	opentelemetry "github.com/tetratelabs/telemetry-opentelemetry"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
 	"istio.io/istio/pkg/lazy"
 	"istio.io/istio/pkg/maps"
 	"istio.io/istio/pkg/test"
@@ -45,29 +44,37 @@ type metricKey struct {
 	attrs attribute.Set
 }
 
-var metricSink telemetry.MetricSink
-
-func SetSink(ms telemetry.MetricSink) {
-	metricSink = ms
+type testExporter struct {
+	reg *prometheus.Registry
+	exp *otelprom.Exporter
 }
 
-var reg = lazy.New(func() (prometheus.Gatherer, error) {
-	// TODO: do not use a global and/or add a way to reset (https://github.com/open-telemetry/opentelemetry-go/issues/4291)
-	opentelemetry.Start(metricSink)
+var exporter = lazy.New(func() (*testExporter, error) {
 	reg := prometheus.NewRegistry()
-	_, err := otelprom.RegisterPrometheusExporter(metricSink, reg, reg)
+	// assertions use the metric and label names as recorded.
+	exp, err := otelprom.NewPrometheusExporter(reg, reg, otelprom.WithUTF8Names())
 	if err != nil {
 		return nil, err
 	}
-	return reg, nil
+	return &testExporter{reg: reg, exp: exp}, nil
 })
 
+// MeterProvider returns the MeterProvider backing the test registry. Sinks
+// under test must be created with opentelemetry.WithMeterProvider using it.
+func MeterProvider() metric.MeterProvider {
+	e, err := exporter.Get()
+	if err != nil {
+		panic(err)
+	}
+	return e.exp.MeterProvider()
+}
+
 func TestRegistry(t test.Failer) prometheus.Gatherer {
-	r, err := reg.Get()
+	e, err := exporter.Get()
 	if err != nil {
 		t.Fatal(err)
 	}
-	return r
+	return e.reg
 }
 
 func New(t test.Failer) *MetricsTest {
@@ -108,6 +115,8 @@ func toMetricKey(row *dto.Metric, metric *dto.MetricFamily) metricKey {
 
 type Compare func(any) error
 
+// DoesNotExist asserts the metric does not exist. If tags are provided, it
+// asserts the metric has no row carrying those tags.
 func DoesNotExist(any) error {
 	// special case logic in the Assert
 	return nil
@@ -166,9 +175,17 @@ func (m *MetricsTest) Assert(name string, tags map[string]string, compare Compar
 		}
 		if fmt.Sprintf("%p", compare) == fmt.Sprintf("%p", DoesNotExist) {
 			for _, metric := range res {
-				if *metric.Name == name {
+				if *metric.Name != name {
+					continue
+				}
+				if tags == nil {
 					return fmt.Errorf("metric was found when it should not have been")
 				}
+				for _, row := range metric.Metric {
+					if m.matches(row, tags) {
+						return fmt.Errorf("metric row was found when it should not have been")
+					}
+				}
 			}
 			return nil
 		}
@@ -177,18 +194,7 @@ func (m *MetricsTest) Assert(name string, tags map[string]string, compare Compar
 				continue
 			}
 			for _, row := range metric.Metric {
-				want := maps.Clone(tags)
-				for _, lv := range row.Label {
-					k, v := *lv.Name, *lv.Value
-					if want[k] == v {
-						delete(want, k)
-					} else {
-						m.t.Logf("skip metric: want %v=%v, got %v=%v", k, want[k], k, v)
-					}
-				}
-				if len(want) > 0 {
-					// Not a match
-					m.t.Logf("skip metric: missing labels: %+v", want)
+				if !m.matches(row, tags) {
 					continue
 				}
 				var v any
@@ -233,6 +239,25 @@ func (m *MetricsTest) Assert(name string, tags map[string]string, compare Compar
 	}
 }
 
+// matches reports whether the row carries all the provided tags.
+func (m *MetricsTest) matches(row *dto.Metric, tags map[string]string) bool {
+	want := maps.Clone(tags)
+	for _, lv := range row.Label {
+		k, v := *lv.Name, *lv.Value
+		if want[k] == v {
+			delete(want, k)
+		} else {
+			m.t.Logf("skip metric: want %v=%v, got %v=%v", k, want[k], k, v)
+		}
+	}
+	if len(want) > 0 {
+		// Not a match
+		m.t.Logf("skip metric: missing labels: %+v", want)
+		return false
+	}
+	return true
+}
+
 func toFloat(r interface{}) float64 {
 	switch v := r.(type) {
 	default:
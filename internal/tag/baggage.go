@@ -0,0 +1,281 @@
+// Copyright (c) Tetrate, Inc 2023.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tag
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// BaggageHeader is the name of the HTTP header carrying W3C baggage.
+const BaggageHeader = "baggage"
+
+// baggageTTLProperty is the list-member property carrying the hops left to
+// tags with a limited TTL.
+const baggageTTLProperty = "ttl"
+
+// baggageTypeProperty is the list-member property carrying the type of tags
+// holding an int64 or a bool, with the values below.
+const (
+	baggageTypeProperty = "type"
+	baggageTypeInt64    = "int64"
+	baggageTypeBool     = "bool"
+)
+
+// Limits of a baggage header set by https://www.w3.org/TR/baggage/#limits.
+const (
+	maxBaggageMembers = 64
+	maxBaggageBytes   = 8192
+)
+
+var (
+	errBaggageTooLarge   = fmt.Errorf("invalid baggage: more than %d bytes", maxBaggageBytes)
+	errBaggageTooMany    = fmt.Errorf("invalid baggage: more than %d list-members", maxBaggageMembers)
+	errBaggageMalformed  = errors.New("invalid baggage: malformed list-member")
+	errBaggageInvalidKey = errors.New("invalid baggage: key is not a token")
+)
+
+// EncodeBaggage encodes the tag map into the W3C baggage header format
+// (https://www.w3.org/TR/baggage/), which allows tags to be propagated across
+// HTTP hops. Tags with TTLNoPropagation are left out, as are tags whose key is
+// not a valid baggage key. The hops left to tags with a limited TTL are carried
+// by a ttl property, e.g. k1=v1;ttl=2, and the type of int64 and bool tags by a
+// type property, e.g. k2=42;type=int64. Tags are encoded in key order until
+// the size or list-member limits of the specification would be exceeded; the
+// remaining tags are dropped whole.
+func EncodeBaggage(m *Map) string {
+	if m == nil || len(m.m) == 0 {
+		return ""
+	}
+	keys := make([]Key, 0, len(m.m))
+	for k, v := range m.m {
+		if v.m.ttl.ttl != valueTTLNoPropagation && isToken(k.name) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].name < keys[j].name })
+
+	var (
+		b       strings.Builder
+		members int
+	)
+	for _, k := range keys {
+		v := m.m[k]
+		member := k.name + "=" + escapeBaggageValue(v.value)
+		switch v.typ {
+		case ValueTypeInt64:
+			member += ";" + baggageTypeProperty + "=" + baggageTypeInt64
+		case ValueTypeBool:
+			member += ";" + baggageTypeProperty + "=" + baggageTypeBool
+		}
+		if v.m.ttl.ttl > 0 {
+			member += ";" + baggageTTLProperty + "=" + strconv.Itoa(v.m.ttl.ttl)
+		}
+		size := len(member)
+		if members > 0 {
+			size++ // the separating comma
+		}
+		if members == maxBaggageMembers || b.Len()+size > maxBaggageBytes {
+			break
+		}
+		if members > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(member)
+		members++
+	}
+	return b.String()
+}
+
+// DecodeBaggage decodes the given W3C baggage header into a tag map. List-member
+// properties other than ttl and type are ignored. Like Decode, the hop count
+// carried by the ttl property is decremented, and values are restored with the
+// type carried by the type property. Members whose key or value cannot be
+// represented as a tag, or whose ttl or typed value is invalid, are skipped.
+// Headers exceeding the limits of the specification or holding malformed
+// list-members are rejected.
+func DecodeBaggage(header string) (*Map, error) {
+	ts := newMap()
+	if err := decodeBaggageEach(header, ts.upsertContent); err != nil {
+		// no partial failures
+		return nil, err
+	}
+	return ts, nil
+}
+
+// DecodeBaggageEach decodes the given W3C baggage header, calling handler for
+// each tag key and value decoded. Typed values are provided in their string
+// representation.
+func DecodeBaggageEach(header string, fn func(key Key, val string, md metadatas)) error {
+	return decodeBaggageEach(header, func(key Key, tc tagContent) {
+		fn(key, tc.value, tc.m)
+	})
+}
+
+func decodeBaggageEach(header string, fn func(key Key, tc tagContent)) error {
+	if len(header) > maxBaggageBytes {
+		return errBaggageTooLarge
+	}
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return nil
+	}
+	members := strings.Split(header, ",")
+	if len(members) > maxBaggageMembers {
+		return errBaggageTooMany
+	}
+	for _, member := range members {
+		// properties follow the value, separated by semicolons.
+		kv, props, _ := strings.Cut(member, ";")
+		if trimOWS(kv) == "" {
+			// tolerate empty list-members, such as trailing commas.
+			continue
+		}
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			return errBaggageMalformed
+		}
+		k, v = trimOWS(k), trimOWS(v)
+		if !isToken(k) {
+			return errBaggageInvalidKey
+		}
+		if !isBaggageValue(v) {
+			return errBaggageMalformed
+		}
+		val, err := url.PathUnescape(v)
+		if err != nil {
+			return errBaggageMalformed
+		}
+		key, err := NewKey(k)
+		if err != nil || !checkValue(val) {
+			continue
+		}
+		ttl, ok := baggageTTL(props)
+		if !ok {
+			continue
+		}
+		tc, ok := baggageContent(val, props, createMetadatas(WithTTL(ttl)))
+		if !ok {
+			continue
+		}
+		fn(key, tc)
+	}
+	return nil
+}
+
+// baggageContent returns the content of a list-member with the provided value
+// and properties, typed as carried by the type property. It reports false if
+// the value is invalid for its type. Unknown types are decoded as strings.
+func baggageContent(val, props string, md metadatas) (tagContent, bool) {
+	switch baggageProperty(props, baggageTypeProperty) {
+	case baggageTypeInt64:
+		n, err := strconv.ParseInt(val, 10, 64)
+		return int64Content(n, md), err == nil
+	case baggageTypeBool:
+		b, err := strconv.ParseBool(val)
+		return boolContent(b, md), err == nil
+	default:
+		return stringContent(val, md), true
+	}
+}
+
+// baggageProperty returns the value of the named property, or "" if absent.
+func baggageProperty(props, name string) string {
+	for _, prop := range strings.Split(props, ";") {
+		if k, v, _ := strings.Cut(prop, "="); trimOWS(k) == name {
+			return trimOWS(v)
+		}
+	}
+	return ""
+}
+
+// baggageTTL returns the TTL of a list-member with the provided properties,
+// after this hop. It reports false if the ttl property is invalid.
+func baggageTTL(props string) (TTL, bool) {
+	for _, prop := range strings.Split(props, ";") {
+		k, v, _ := strings.Cut(prop, "=")
+		if trimOWS(k) != baggageTTLProperty {
+			continue
+		}
+		hops, err := strconv.Atoi(trimOWS(v))
+		if err != nil || hops <= 0 {
+			return TTL{}, false
+		}
+		// this hop consumes one of the hops left.
+		return NewTTL(hops - 1), true
+	}
+	return TTLUnlimitedPropagation, true
+}
+
+func trimOWS(s string) string {
+	return strings.Trim(s, " \t")
+}
+
+// isToken reports whether s is an RFC 7230 token, which baggage keys must be.
+func isToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= '0' && c <= '9', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// isBaggageOctet reports whether c may appear unescaped in a baggage value.
+func isBaggageOctet(c byte) bool {
+	return c == 0x21 ||
+		(c >= 0x23 && c <= 0x2B) ||
+		(c >= 0x2D && c <= 0x3A) ||
+		(c >= 0x3C && c <= 0x5B) ||
+		(c >= 0x5D && c <= 0x7E)
+}
+
+func isBaggageValue(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isBaggageOctet(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// escapeBaggageValue percent-encodes the characters of s which may not appear
+// in a baggage value, including the percent sign itself.
+func escapeBaggageValue(s string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if isBaggageOctet(c) && c != '%' {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[c>>4])
+		b.WriteByte(hex[c&0x0F])
+	}
+	return b.String()
+}
@@ -0,0 +1,213 @@
+// Copyright (c) Tetrate, Inc 2023.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tag
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestEncodeDecodeBaggage(t *testing.T) {
+	k1, _ := NewKey("k1")
+	k2, _ := NewKey("k2")
+	k3, _ := NewKey("k3")
+	k4, _ := NewKey("k4 is not a token")
+
+	ctx, err := New(context.Background(),
+		Insert(k1, "v1"),
+		Insert(k2, "v2 is, weird=%;\"\\"),
+		Insert(k3, "v3", WithTTL(TTLNoPropagation)),
+		Insert(k4, "v4"),
+	)
+	if err != nil {
+		t.Fatalf("New = %v", err)
+	}
+
+	encoded := EncodeBaggage(FromContext(ctx))
+	if want := "k1=v1,k2=v2%20is%2C%20weird=%25%3B%22%5C"; encoded != want {
+		t.Errorf("EncodeBaggage = %q; want %q", encoded, want)
+	}
+
+	decoded, err := DecodeBaggage(encoded)
+	if err != nil {
+		t.Fatalf("DecodeBaggage = %v", err)
+	}
+	if got, want := len(decoded.m), 2; got != want {
+		t.Errorf("decoded %d tags; want %d", got, want)
+	}
+	for k, want := range map[Key]string{k1: "v1", k2: "v2 is, weird=%;\"\\"} {
+		if got, ok := decoded.Value(k); !ok || got != want {
+			t.Errorf("decoded %v = %q; want %q", k.Name(), got, want)
+		}
+	}
+}
+
+func TestEncodeDecodeBaggageTTL(t *testing.T) {
+	k1, _ := NewKey("k1")
+	k2, _ := NewKey("k2")
+
+	ctx, _ := New(context.Background(),
+		Insert(k1, "v1", WithTTL(NewTTL(2))),
+		Insert(k2, "v2"),
+	)
+	encoded := EncodeBaggage(FromContext(ctx))
+	if want := "k1=v1;ttl=2,k2=v2"; encoded != want {
+		t.Errorf("EncodeBaggage = %q; want %q", encoded, want)
+	}
+
+	// each hop consumes one of the hops left, until the tag is dropped.
+	for _, want := range []string{"k1=v1;ttl=1,k2=v2", "k2=v2", "k2=v2"} {
+		decoded, err := DecodeBaggage(encoded)
+		if err != nil {
+			t.Fatalf("DecodeBaggage = %v", err)
+		}
+		if got, ok := decoded.Value(k2); !ok || got != "v2" {
+			t.Errorf("decoded k2 = %q; want %q", got, "v2")
+		}
+		if encoded = EncodeBaggage(decoded); encoded != want {
+			t.Errorf("EncodeBaggage = %q; want %q", encoded, want)
+		}
+	}
+}
+
+func TestEncodeDecodeBaggageTypes(t *testing.T) {
+	k1, _ := NewKey("k1")
+	k2, _ := NewKey("k2")
+	k3, _ := NewKey("k3")
+	k4, _ := NewKey("k4")
+
+	ctx, _ := New(context.Background(),
+		Insert(k1, "42"),
+		InsertInt64(k2, -42),
+		InsertBool(k3, true),
+		InsertBool(k4, false, WithTTL(NewTTL(3))),
+	)
+	encoded := EncodeBaggage(FromContext(ctx))
+	if want := "k1=42,k2=-42;type=int64,k3=true;type=bool,k4=false;type=bool;ttl=3"; encoded != want {
+		t.Errorf("EncodeBaggage = %q; want %q", encoded, want)
+	}
+
+	decoded, err := DecodeBaggage(encoded)
+	if err != nil {
+		t.Fatalf("DecodeBaggage = %v", err)
+	}
+	want := []Tag{
+		{Key: k1, Value: "42", Type: ValueTypeString},
+		{Key: k2, Value: "-42", Type: ValueTypeInt64, Int64: -42},
+		{Key: k3, Value: "true", Type: ValueTypeBool, Int64: 1, Bool: true},
+		{Key: k4, Value: "false", Type: ValueTypeBool},
+	}
+	for _, w := range want {
+		if got, ok := decoded.Tag(w.Key); !ok || got != w {
+			t.Errorf("decoded tag %v = %+v; want %+v", w.Key.Name(), got, w)
+		}
+	}
+}
+
+func TestEncodeBaggageLimits(t *testing.T) {
+	var mods []Mutator
+	for i := 0; i < maxBaggageMembers+10; i++ {
+		k, _ := NewKey(fmt.Sprintf("k%03d", i))
+		mods = append(mods, Insert(k, "v"))
+	}
+	ctx, _ := New(context.Background(), mods...)
+	encoded := EncodeBaggage(FromContext(ctx))
+	if got := len(strings.Split(encoded, ",")); got != maxBaggageMembers {
+		t.Errorf("encoded %d members; want %d", got, maxBaggageMembers)
+	}
+	if !strings.HasPrefix(encoded, "k000=v,") {
+		t.Errorf("EncodeBaggage = %q; want members in key order", encoded)
+	}
+
+	mods = mods[:0]
+	for i := 0; i < 4; i++ {
+		k, _ := NewKey(fmt.Sprintf("k%d", i))
+		mods = append(mods, Insert(k, strings.Repeat("v", 250)))
+	}
+	big, _ := NewKey("big")
+	// escaped, this value alone fills most of the header.
+	mods = append(mods, Insert(big, strings.Repeat(" ", 250)))
+	ctx, _ = New(context.Background(), mods...)
+	encoded = EncodeBaggage(FromContext(ctx))
+	if len(encoded) > maxBaggageBytes {
+		t.Errorf("encoded %d bytes; want at most %d", len(encoded), maxBaggageBytes)
+	}
+	if _, err := DecodeBaggage(encoded); err != nil {
+		t.Errorf("DecodeBaggage(EncodeBaggage()) = %v", err)
+	}
+}
+
+func TestDecodeBaggage(t *testing.T) {
+	k1, _ := NewKey("k1")
+	k2, _ := NewKey("k2")
+	k3, _ := NewKey("k3")
+
+	tooMany := make([]string, maxBaggageMembers+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("k%d=v", i)
+	}
+
+	tests := []struct {
+		name    string
+		header  string
+		want    map[Key]string
+		wantErr error
+	}{
+		{"empty", "", map[Key]string{}, nil},
+		{"single", "k1=v1", map[Key]string{k1: "v1"}, nil},
+		{"whitespace", " k1 = v1 ,\tk2=v2\t", map[Key]string{k1: "v1", k2: "v2"}, nil},
+		{"properties", "k1=v1;p1;p2=x,k2=v2", map[Key]string{k1: "v1", k2: "v2"}, nil},
+		{"escaped", "k1=a%20b%2Cc", map[Key]string{k1: "a b,c"}, nil},
+		{"empty value", "k1=", map[Key]string{k1: ""}, nil},
+		{"empty member", "k1=v1,,k2=v2,", map[Key]string{k1: "v1", k2: "v2"}, nil},
+		{"last wins", "k1=v1,k1=v2", map[Key]string{k1: "v2"}, nil},
+		{"unrepresentable value", "k1=%00,k2=v2", map[Key]string{k2: "v2"}, nil},
+		{"invalid ttl", "k1=v1;ttl=0,k2=v2;ttl=x,k3=v3", map[Key]string{k3: "v3"}, nil},
+		{"invalid typed value", "k1=x;type=int64,k2=x;type=bool,k3=v3", map[Key]string{k3: "v3"}, nil},
+		{"unknown type", "k1=v1;type=float", map[Key]string{k1: "v1"}, nil},
+		{"missing equals", "k1", nil, errBaggageMalformed},
+		{"invalid key", "k 1=v1", nil, errBaggageInvalidKey},
+		{"empty key", "=v1", nil, errBaggageInvalidKey},
+		{"invalid value", "k1=a b", nil, errBaggageMalformed},
+		{"invalid escape", "k1=%zz", nil, errBaggageMalformed},
+		{"too many", strings.Join(tooMany, ","), nil, errBaggageTooMany},
+		{"too large", "k1=" + strings.Repeat("v", maxBaggageBytes), nil, errBaggageTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := DecodeBaggage(tt.header)
+			if err != tt.wantErr {
+				t.Fatalf("DecodeBaggage(%q) error = %v; want %v", tt.header, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if len(m.m) != len(tt.want) {
+				t.Errorf("DecodeBaggage(%q) = %v; want %v", tt.header, m, tt.want)
+			}
+			for k, want := range tt.want {
+				if got, ok := m.Value(k); !ok || got != want {
+					t.Errorf("DecodeBaggage(%q)[%v] = %q; want %q", tt.header, k.Name(), got, want)
+				}
+				if md := m.m[k].m; md.ttl != TTLUnlimitedPropagation {
+					t.Errorf("DecodeBaggage(%q)[%v] ttl = %v; want unlimited", tt.header, k.Name(), md.ttl)
+				}
+			}
+		})
+	}
+}
@@ -40,6 +40,24 @@ func NewContext(ctx context.Context, m *Map) context.Context {
 	return context.WithValue(ctx, mapCtxKey, m)
 }
 
+// Merge returns a copy of ctx holding the tags of m, typically received from
+// the wire, merged with the tags of the tag map already found in ctx, if any.
+// Tags already found in ctx take precedence.
+func Merge(ctx context.Context, m *Map) context.Context {
+	orig := FromContext(ctx)
+	if orig == nil || len(orig.m) == 0 {
+		return NewContext(ctx, m)
+	}
+	merged := newMap()
+	for k, v := range m.m {
+		merged.insertContent(k, v)
+	}
+	for k, v := range orig.m {
+		merged.upsertContent(k, v)
+	}
+	return NewContext(ctx, merged)
+}
+
 type ctxKey struct{}
 
 var mapCtxKey = ctxKey{}
@@ -22,6 +22,7 @@ instrumentation data.
 
 Tags can be propagated on the wire and in the same process via context.Context.
 Encode and Decode should be used to represent tags into their binary propagation
-form.
+form. EncodeBaggage and DecodeBaggage represent tags in the W3C baggage header
+format instead.
 */
 package tag
@@ -21,17 +21,64 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"strconv"
+)
+
+// ValueType is the type of the value of a tag.
+type ValueType byte
+
+const (
+	// ValueTypeString is the type of tags holding a string.
+	ValueTypeString ValueType = iota
+	// ValueTypeInt64 is the type of tags holding an int64.
+	ValueTypeInt64
+	// ValueTypeBool is the type of tags holding a bool.
+	ValueTypeBool
 )
 
 // Tag is a key value pair that can be propagated on wire.
+// Value holds the string representation of typed values, which are found in
+// Int64 or Bool depending on Type.
 type Tag struct {
 	Key   Key
 	Value string
+	Type  ValueType
+	Int64 int64
+	Bool  bool
 }
 
 type tagContent struct {
 	value string
 	m     metadatas
+	typ   ValueType
+	// num holds int64 values, and 1 or 0 for bool values.
+	num int64
+}
+
+func stringContent(v string, md metadatas) tagContent {
+	return tagContent{value: v, m: md}
+}
+
+func int64Content(v int64, md metadatas) tagContent {
+	return tagContent{value: strconv.FormatInt(v, 10), m: md, typ: ValueTypeInt64, num: v}
+}
+
+func boolContent(v bool, md metadatas) tagContent {
+	tc := tagContent{value: strconv.FormatBool(v), m: md, typ: ValueTypeBool}
+	if v {
+		tc.num = 1
+	}
+	return tc
+}
+
+func (tc tagContent) tag(k Key) Tag {
+	return Tag{
+		Key:   k,
+		Value: tc.value,
+		Type:  tc.typ,
+		Int64: tc.num,
+		Bool:  tc.typ == ValueTypeBool && tc.num == 1,
+	}
 }
 
 // Map is a map of tags. Use New to create a context containing
@@ -53,13 +100,40 @@ func (m *Map) Value(k Key) (string, bool) {
 	return v.value, ok
 }
 
+// Tag returns the tag for the key if a value for the key exists.
+func (m *Map) Tag(k Key) (Tag, bool) {
+	if m == nil {
+		return Tag{}, false
+	}
+	tc, ok := m.m[k]
+	if !ok {
+		return Tag{}, false
+	}
+	return tc.tag(k), true
+}
+
 // Iterate over all Tags found in map.
 func (m *Map) Iterate(cb func(t Tag)) {
 	for key, tc := range m.m {
-		cb(Tag{Key: key, Value: tc.value})
+		cb(tc.tag(key))
 	}
 }
 
+// Filter returns a copy of the map holding the tags whose key is accepted by
+// keep.
+func (m *Map) Filter(keep func(k Key) bool) *Map {
+	filtered := newMap()
+	if m == nil {
+		return filtered
+	}
+	for k, v := range m.m {
+		if keep(k) {
+			filtered.insertContent(k, v)
+		}
+	}
+	return filtered
+}
+
 func (m *Map) String() string {
 	if m == nil {
 		return "nil"
@@ -80,20 +154,32 @@ func (m *Map) String() string {
 }
 
 func (m *Map) insert(k Key, v string, md metadatas) {
+	m.insertContent(k, stringContent(v, md))
+}
+
+func (m *Map) update(k Key, v string, md metadatas) {
+	m.updateContent(k, stringContent(v, md))
+}
+
+func (m *Map) upsert(k Key, v string, md metadatas) {
+	m.upsertContent(k, stringContent(v, md))
+}
+
+func (m *Map) insertContent(k Key, tc tagContent) {
 	if _, ok := m.m[k]; ok {
 		return
 	}
-	m.m[k] = tagContent{value: v, m: md}
+	m.m[k] = tc
 }
 
-func (m *Map) update(k Key, v string, md metadatas) {
+func (m *Map) updateContent(k Key, tc tagContent) {
 	if _, ok := m.m[k]; ok {
-		m.m[k] = tagContent{value: v, m: md}
+		m.m[k] = tc
 	}
 }
 
-func (m *Map) upsert(k Key, v string, md metadatas) {
-	m.m[k] = tagContent{value: v, m: md}
+func (m *Map) upsertContent(k Key, tc tagContent) {
+	m.m[k] = tc
 }
 
 func (m *Map) delete(k Key) {
@@ -167,6 +253,51 @@ func Upsert(k Key, v string, mds ...Metadata) Mutator {
 	}
 }
 
+// InsertInt64 returns a mutator that inserts an int64 value associated with k,
+// like Insert.
+func InsertInt64(k Key, v int64, mds ...Metadata) Mutator {
+	return contentMutator(k, (*Map).insertContent, int64Content(v, createMetadatas(mds...)))
+}
+
+// UpdateInt64 returns a mutator that updates the value of the tag associated
+// with k with the int64 v, like Update.
+func UpdateInt64(k Key, v int64, mds ...Metadata) Mutator {
+	return contentMutator(k, (*Map).updateContent, int64Content(v, createMetadatas(mds...)))
+}
+
+// UpsertInt64 returns a mutator that upserts the value of the tag associated
+// with k with the int64 v, like Upsert.
+func UpsertInt64(k Key, v int64, mds ...Metadata) Mutator {
+	return contentMutator(k, (*Map).upsertContent, int64Content(v, createMetadatas(mds...)))
+}
+
+// InsertBool returns a mutator that inserts a bool value associated with k,
+// like Insert.
+func InsertBool(k Key, v bool, mds ...Metadata) Mutator {
+	return contentMutator(k, (*Map).insertContent, boolContent(v, createMetadatas(mds...)))
+}
+
+// UpdateBool returns a mutator that updates the value of the tag associated
+// with k with the bool v, like Update.
+func UpdateBool(k Key, v bool, mds ...Metadata) Mutator {
+	return contentMutator(k, (*Map).updateContent, boolContent(v, createMetadatas(mds...)))
+}
+
+// UpsertBool returns a mutator that upserts the value of the tag associated
+// with k with the bool v, like Upsert.
+func UpsertBool(k Key, v bool, mds ...Metadata) Mutator {
+	return contentMutator(k, (*Map).upsertContent, boolContent(v, createMetadatas(mds...)))
+}
+
+func contentMutator(k Key, set func(m *Map, k Key, tc tagContent), tc tagContent) Mutator {
+	return &mutator{
+		fn: func(m *Map) (*Map, error) {
+			set(m, k, tc)
+			return m, nil
+		},
+	}
+}
+
 func createMetadatas(mds ...Metadata) metadatas {
 	var metas metadatas
 	if len(mds) > 0 {
@@ -207,7 +338,7 @@ func New(ctx context.Context, mutator ...Mutator) (context.Context, error) {
 			if !checkValue(v.value) {
 				return ctx, fmt.Errorf("key:%q value:%q: %v", k.Name(), v, errInvalidValue)
 			}
-			m.insert(k, v.value, v.m)
+			m.insertContent(k, v)
 		}
 	}
 	var err error
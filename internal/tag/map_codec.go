@@ -21,8 +21,7 @@ import (
 	"fmt"
 )
 
-// KeyType defines the types of keys allowed. Currently only keyTypeString is
-// supported.
+// KeyType defines the types of keys allowed.
 type keyType byte
 
 const (
@@ -31,7 +30,12 @@ const (
 	keyTypeTrue
 	keyTypeFalse
 
-	tagsVersionID = byte(0)
+	// tagsVersionIDUnlimited is the version of encodings holding tags with
+	// unlimited propagation only.
+	tagsVersionIDUnlimited = byte(0)
+	// tagsVersionID is the version of encodings followed by the TTL of each
+	// tag.
+	tagsVersionID = byte(1)
 )
 
 type encoderGRPC struct {
@@ -99,6 +103,20 @@ func (eg *encoderGRPC) writeUint64(i uint64) {
 	eg.writeIdx += 8
 }
 
+func (eg *encoderGRPC) writeVarint(i int64) {
+	eg.growIfRequired(binary.MaxVarintLen64)
+	eg.writeIdx += binary.PutVarint(eg.buf[eg.writeIdx:], i)
+}
+
+func (eg *encoderGRPC) readVarint() (int64, error) {
+	i, n := binary.Varint(eg.buf[eg.readIdx:])
+	if n <= 0 {
+		return 0, fmt.Errorf("unexpected end while readVarint '%x' starting at idx '%v'", eg.buf, eg.readIdx)
+	}
+	eg.readIdx += n
+	return i, nil
+}
+
 func (eg *encoderGRPC) readByte() byte {
 	b := eg.buf[eg.readIdx]
 	eg.readIdx++
@@ -161,20 +179,41 @@ func (eg *encoderGRPC) bytes() []byte {
 }
 
 // Encode encodes the tag map into a []byte. It is useful to propagate
-// the tag maps on wire in binary format.
+// the tag maps on wire in binary format. Tags with TTLNoPropagation, or which
+// ran out of hops, are left out. Tag maps holding tags with unlimited
+// propagation only are encoded in the version 0 format, understood by
+// decoders which are unaware of hop counts.
 func Encode(m *Map) []byte {
 	if m == nil {
 		return nil
 	}
+	version := tagsVersionIDUnlimited
+	for _, v := range m.m {
+		if v.m.ttl.ttl > 0 {
+			version = tagsVersionID
+			break
+		}
+	}
 	eg := &encoderGRPC{
 		buf: make([]byte, len(m.m)),
 	}
-	eg.writeByte(tagsVersionID)
+	eg.writeByte(version)
 	for k, v := range m.m {
-		if v.m.ttl.ttl == valueTTLUnlimitedPropagation {
-			eg.writeByte(byte(keyTypeString))
-			eg.writeStringWithVarintLen(k.name)
-			eg.writeBytesWithVarintLen([]byte(v.value))
+		if v.m.ttl.ttl == valueTTLNoPropagation {
+			continue
+		}
+		switch {
+		case v.typ == ValueTypeInt64:
+			eg.writeTagUint64(k.name, uint64(v.num))
+		case v.typ == ValueTypeBool && v.num == 1:
+			eg.writeTagTrue(k.name)
+		case v.typ == ValueTypeBool:
+			eg.writeTagFalse(k.name)
+		default:
+			eg.writeTagString(k.name, v.value)
+		}
+		if version == tagsVersionID {
+			eg.writeVarint(int64(v.m.ttl.ttl))
 		}
 	}
 	return eg.bytes()
@@ -183,7 +222,7 @@ func Encode(m *Map) []byte {
 // Decode decodes the given []byte into a tag map.
 func Decode(bytes []byte) (*Map, error) {
 	ts := newMap()
-	err := DecodeEach(bytes, ts.upsert)
+	err := decodeEach(bytes, ts.upsertContent)
 	if err != nil {
 		// no partial failures
 		return nil, err
@@ -192,8 +231,16 @@ func Decode(bytes []byte) (*Map, error) {
 }
 
 // DecodeEach decodes the given serialized tag map, calling handler for each
-// tag key and value decoded.
+// tag key and value decoded. Typed values are provided in their string
+// representation. The hop count of tags with a limited TTL is decremented, so
+// tags received on their last hop are not propagated further.
 func DecodeEach(bytes []byte, fn func(key Key, val string, md metadatas)) error {
+	return decodeEach(bytes, func(key Key, tc tagContent) {
+		fn(key, tc.value, tc.m)
+	})
+}
+
+func decodeEach(bytes []byte, fn func(key Key, tc tagContent)) error {
 	eg := &encoderGRPC{
 		buf: bytes,
 	}
@@ -209,7 +256,7 @@ func DecodeEach(bytes []byte, fn func(key Key, val string, md metadatas)) error
 	for !eg.readEnded() {
 		typ := keyType(eg.readByte())
 
-		if typ != keyTypeString {
+		if typ > keyTypeFalse {
 			return fmt.Errorf("cannot decode: invalid key type: %q", typ)
 		}
 
@@ -217,24 +264,48 @@ func DecodeEach(bytes []byte, fn func(key Key, val string, md metadatas)) error
 		if err != nil {
 			return err
 		}
-
-		v, err := eg.readBytesWithVarintLen()
-		if err != nil {
-			return err
-		}
-
 		key, err := NewKey(string(k))
 		if err != nil {
 			return err
 		}
-		val := string(v)
-		if !checkValue(val) {
-			return errInvalidValue
+
+		var tc tagContent
+		switch typ {
+		case keyTypeString:
+			v, err := eg.readBytesWithVarintLen()
+			if err != nil {
+				return err
+			}
+			val := string(v)
+			if !checkValue(val) {
+				return errInvalidValue
+			}
+			tc = stringContent(val, metadatas{})
+		case keyTypeInt64:
+			if len(eg.buf)-eg.readIdx < 8 {
+				return fmt.Errorf("unexpected end while readUint64 '%x' starting at idx '%v'", eg.buf, eg.readIdx)
+			}
+			tc = int64Content(int64(eg.readUint64()), metadatas{})
+		default:
+			tc = boolContent(typ == keyTypeTrue, metadatas{})
 		}
-		fn(key, val, createMetadatas(WithTTL(TTLUnlimitedPropagation)))
-		if err != nil {
-			return err
+
+		tc.m.ttl = TTLUnlimitedPropagation
+		if version == tagsVersionID {
+			hops, err := eg.readVarint()
+			if err != nil {
+				return err
+			}
+			switch {
+			case hops == valueTTLUnlimitedPropagation:
+			case hops > 0:
+				// this hop consumes one of the hops left.
+				tc.m.ttl = NewTTL(int(hops - 1))
+			default:
+				return fmt.Errorf("cannot decode: invalid ttl: %d", hops)
+			}
 		}
+		fn(key, tc)
 	}
 	return nil
 }
@@ -105,6 +105,89 @@ func TestEncodeDecode(t *testing.T) {
 	}
 }
 
+func TestEncodeDecodeTyped(t *testing.T) {
+	k1, _ := NewKey("k1")
+	k2, _ := NewKey("k2")
+	k3, _ := NewKey("k3")
+	k4, _ := NewKey("k4")
+
+	ctx, _ := New(context.Background(),
+		Insert(k1, "v1"),
+		InsertInt64(k2, -42),
+		InsertBool(k3, true),
+		InsertBool(k4, false, WithTTL(NewTTL(3))),
+	)
+	decoded, err := Decode(Encode(FromContext(ctx)))
+	if err != nil {
+		t.Fatalf("Decode = %v", err)
+	}
+
+	want := []Tag{
+		{Key: k1, Value: "v1", Type: ValueTypeString},
+		{Key: k2, Value: "-42", Type: ValueTypeInt64, Int64: -42},
+		{Key: k3, Value: "true", Type: ValueTypeBool, Int64: 1, Bool: true},
+		{Key: k4, Value: "false", Type: ValueTypeBool},
+	}
+	for _, w := range want {
+		if got, ok := decoded.Tag(w.Key); !ok || got != w {
+			t.Errorf("decoded tag %v = %+v; want %+v", w.Key.Name(), got, w)
+		}
+	}
+	if got := decoded.m[k4].m.ttl; got != NewTTL(2) {
+		t.Errorf("decoded ttl = %v; want %v", got, NewTTL(2))
+	}
+
+	var strs []string
+	if err = DecodeEach(Encode(decoded), func(k Key, v string, _ metadatas) {
+		strs = append(strs, k.Name()+"="+v)
+	}); err != nil {
+		t.Fatalf("DecodeEach = %v", err)
+	}
+	sort.Strings(strs)
+	if want := []string{"k1=v1", "k2=-42", "k3=true", "k4=false"}; !reflect.DeepEqual(strs, want) {
+		t.Errorf("DecodeEach = %v; want %v", strs, want)
+	}
+}
+
+func TestEncodeDecodeTTL(t *testing.T) {
+	k1, _ := NewKey("k1")
+	k2, _ := NewKey("k2")
+	k3, _ := NewKey("k3")
+
+	ctx, _ := New(context.Background(),
+		Insert(k1, "v1", WithTTL(TTLUnlimitedPropagation)),
+		Insert(k2, "v2", WithTTL(NewTTL(2))),
+		Insert(k3, "v3", WithTTL(TTLNoPropagation)),
+	)
+
+	// each hop decodes the tags received from the previous one.
+	wantTTLs := []map[Key]TTL{
+		{k1: TTLUnlimitedPropagation, k2: NewTTL(1)},
+		{k1: TTLUnlimitedPropagation, k2: TTLNoPropagation},
+		{k1: TTLUnlimitedPropagation},
+	}
+	m := FromContext(ctx)
+	for hop, want := range wantTTLs {
+		encoded := Encode(m)
+		var err error
+		if m, err = Decode(encoded); err != nil {
+			t.Fatalf("hop %d: Decode = %v", hop, err)
+		}
+		got := make(map[Key]TTL, m.Len())
+		for k, v := range m.m {
+			got[k] = v.m.ttl
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("hop %d: decoded ttls = %v; want %v", hop, got, want)
+		}
+	}
+
+	// tag maps without hop counts remain decodable by version 0 decoders.
+	if encoded := Encode(m); encoded[0] != tagsVersionIDUnlimited {
+		t.Errorf("Encode version = %d; want %d", encoded[0], tagsVersionIDUnlimited)
+	}
+}
+
 func TestDecode(t *testing.T) {
 	k1, _ := NewKey("k1")
 	ctx, _ := New(context.Background(), Insert(k1, "v1"))
@@ -121,6 +204,48 @@ func TestDecode(t *testing.T) {
 			want:    FromContext(ctx),
 			wantErr: false,
 		},
+		{
+			name:    "valid unlimited ttl",
+			bytes:   []byte{1, 0, 2, 107, 49, 2, 118, 49, 1},
+			want:    FromContext(ctx),
+			wantErr: false,
+		},
+		{
+			name:    "valid last hop",
+			bytes:   []byte{1, 0, 2, 107, 49, 1, 49, 2},
+			want:    makeTestTagMapWithMetadata(tagContent{value: "1", m: ttlNoPropMd}),
+			wantErr: false,
+		},
+		{
+			name:    "missing ttl",
+			bytes:   []byte{1, 0, 2, 107, 49, 2, 118, 49},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "no propagation ttl",
+			bytes:   []byte{1, 0, 2, 107, 49, 2, 118, 49, 0},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "unsupported version",
+			bytes:   []byte{2, 0, 2, 107, 49, 2, 118, 49, 1},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "truncated int64",
+			bytes:   []byte{0, 1, 2, 107, 49, 42, 0, 0},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "invalid key type",
+			bytes:   []byte{0, 4, 2, 107, 49},
+			want:    nil,
+			wantErr: true,
+		},
 		{
 			name:    "non-ascii key",
 			bytes:   []byte{0, 0, 2, 107, 49, 2, 118, 49, 0, 2, 107, 25, 2, 118, 49},
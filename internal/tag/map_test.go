@@ -48,6 +48,47 @@ func TestContext(t *testing.T) {
 	}
 }
 
+func TestMerge(t *testing.T) {
+	k1, _ := NewKey("k1")
+	k2, _ := NewKey("k2")
+
+	remote := newMap()
+	remote.insert(k1, "remote", ttlUnlimitedPropMd)
+	remote.insert(k2, "remote", ttlUnlimitedPropMd)
+
+	if got := FromContext(Merge(context.Background(), remote)); got != remote {
+		t.Errorf("Merge without tags = %v; want %v", got, remote)
+	}
+
+	ctx, _ := New(context.Background(), Insert(k1, "local", WithTTL(TTLNoPropagation)))
+	got := FromContext(Merge(ctx, remote))
+	want := newMap()
+	want.insert(k1, "local", ttlNoPropMd)
+	want.insert(k2, "remote", ttlUnlimitedPropMd)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Merge = %v; want %v", got, want)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	k1, _ := NewKey("k1")
+	k2, _ := NewKey("k2")
+
+	m := newMap()
+	m.insert(k1, "v1", ttlUnlimitedPropMd)
+	m.insert(k2, "v2", ttlNoPropMd)
+
+	got := m.Filter(func(k Key) bool { return k == k2 })
+	want := newMap()
+	want.insert(k2, "v2", ttlNoPropMd)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Filter = %v; want %v", got, want)
+	}
+	if m.Len() != 2 {
+		t.Errorf("Filter modified the map: %v", m)
+	}
+}
+
 func TestDo(t *testing.T) {
 	k1, _ := NewKey("k1")
 	k2, _ := NewKey("k2")
@@ -174,6 +215,39 @@ func TestNewMap(t *testing.T) {
 	}
 }
 
+func TestTypedMutators(t *testing.T) {
+	k1, _ := NewKey("k1")
+	k2, _ := NewKey("k2")
+	k3, _ := NewKey("k3")
+
+	ctx, err := New(context.Background(),
+		Insert(k1, "v1"),
+		InsertInt64(k1, 1),
+		UpdateInt64(k2, 2),
+		UpsertBool(k3, false),
+		UpdateBool(k3, true),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := FromContext(ctx)
+
+	want := newMap()
+	want.insert(k1, "v1", ttlUnlimitedPropMd)
+	want.insertContent(k3, boolContent(true, ttlUnlimitedPropMd))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Map = %v; want %v", got, want)
+	}
+
+	ctx, _ = New(ctx, UpsertInt64(k1, 7), UpsertBool(k2, true))
+	if tg, _ := FromContext(ctx).Tag(k1); tg.Type != ValueTypeInt64 || tg.Int64 != 7 || tg.Value != "7" {
+		t.Errorf("Tag(k1) = %+v; want int64 7", tg)
+	}
+	if v, _ := FromContext(ctx).Value(k2); v != "true" {
+		t.Errorf("Value(k2) = %q; want %q", v, "true")
+	}
+}
+
 func TestNewMapWithMetadata(t *testing.T) {
 	k3, _ := NewKey("k3")
 	k4, _ := NewKey("k4")
@@ -193,73 +267,73 @@ func TestNewMapWithMetadata(t *testing.T) {
 				Insert(k4, "4"),
 			},
 			want: makeTestTagMapWithMetadata(
-				tagContent{"5", ttlNoPropMd},
-				tagContent{"4", ttlUnlimitedPropMd}),
+				tagContent{value: "5", m: ttlNoPropMd},
+				tagContent{value: "4", m: ttlUnlimitedPropMd}),
 		},
 		{
 			name:    "from existing; insert existing",
-			initial: makeTestTagMapWithMetadata(tagContent{"5", ttlNoPropMd}),
+			initial: makeTestTagMapWithMetadata(tagContent{value: "5", m: ttlNoPropMd}),
 			mods: []Mutator{
 				Insert(k5, "5", WithTTL(TTLUnlimitedPropagation)),
 			},
-			want: makeTestTagMapWithMetadata(tagContent{"5", ttlNoPropMd}),
+			want: makeTestTagMapWithMetadata(tagContent{value: "5", m: ttlNoPropMd}),
 		},
 		{
 			name:    "from existing; update non-existing",
-			initial: makeTestTagMapWithMetadata(tagContent{"5", ttlNoPropMd}),
+			initial: makeTestTagMapWithMetadata(tagContent{value: "5", m: ttlNoPropMd}),
 			mods: []Mutator{
 				Update(k4, "4", WithTTL(TTLUnlimitedPropagation)),
 			},
-			want: makeTestTagMapWithMetadata(tagContent{"5", ttlNoPropMd}),
+			want: makeTestTagMapWithMetadata(tagContent{value: "5", m: ttlNoPropMd}),
 		},
 		{
 			name: "from existing; update existing",
 			initial: makeTestTagMapWithMetadata(
-				tagContent{"5", ttlUnlimitedPropMd},
-				tagContent{"4", ttlNoPropMd}),
+				tagContent{value: "5", m: ttlUnlimitedPropMd},
+				tagContent{value: "4", m: ttlNoPropMd}),
 			mods: []Mutator{
 				Update(k5, "5"),
 				Update(k4, "4", WithTTL(TTLUnlimitedPropagation)),
 			},
 			want: makeTestTagMapWithMetadata(
-				tagContent{"5", ttlUnlimitedPropMd},
-				tagContent{"4", ttlUnlimitedPropMd}),
+				tagContent{value: "5", m: ttlUnlimitedPropMd},
+				tagContent{value: "4", m: ttlUnlimitedPropMd}),
 		},
 		{
 			name: "from existing; upsert existing",
 			initial: makeTestTagMapWithMetadata(
-				tagContent{"5", ttlNoPropMd},
-				tagContent{"4", ttlNoPropMd}),
+				tagContent{value: "5", m: ttlNoPropMd},
+				tagContent{value: "4", m: ttlNoPropMd}),
 			mods: []Mutator{
 				Upsert(k4, "4", WithTTL(TTLUnlimitedPropagation)),
 			},
 			want: makeTestTagMapWithMetadata(
-				tagContent{"5", ttlNoPropMd},
-				tagContent{"4", ttlUnlimitedPropMd}),
+				tagContent{value: "5", m: ttlNoPropMd},
+				tagContent{value: "4", m: ttlUnlimitedPropMd}),
 		},
 		{
 			name: "from existing; upsert non-existing",
 			initial: makeTestTagMapWithMetadata(
-				tagContent{"5", ttlNoPropMd}),
+				tagContent{value: "5", m: ttlNoPropMd}),
 			mods: []Mutator{
 				Upsert(k4, "4", WithTTL(TTLUnlimitedPropagation)),
 				Upsert(k3, "3"),
 			},
 			want: makeTestTagMapWithMetadata(
-				tagContent{"5", ttlNoPropMd},
-				tagContent{"4", ttlUnlimitedPropMd},
-				tagContent{"3", ttlUnlimitedPropMd}),
+				tagContent{value: "5", m: ttlNoPropMd},
+				tagContent{value: "4", m: ttlUnlimitedPropMd},
+				tagContent{value: "3", m: ttlUnlimitedPropMd}),
 		},
 		{
 			name: "from existing; delete",
 			initial: makeTestTagMapWithMetadata(
-				tagContent{"5", ttlNoPropMd},
-				tagContent{"4", ttlNoPropMd}),
+				tagContent{value: "5", m: ttlNoPropMd},
+				tagContent{value: "4", m: ttlNoPropMd}),
 			mods: []Mutator{
 				Delete(k5),
 			},
 			want: makeTestTagMapWithMetadata(
-				tagContent{"4", ttlNoPropMd}),
+				tagContent{value: "4", m: ttlNoPropMd}),
 		},
 		{
 			name:    "from non-existing; upsert with multiple-metadata",
@@ -269,8 +343,8 @@ func TestNewMapWithMetadata(t *testing.T) {
 				Upsert(k5, "5", WithTTL(TTLNoPropagation), WithTTL(TTLUnlimitedPropagation)),
 			},
 			want: makeTestTagMapWithMetadata(
-				tagContent{"4", ttlNoPropMd},
-				tagContent{"5", ttlUnlimitedPropMd}),
+				tagContent{value: "4", m: ttlNoPropMd},
+				tagContent{value: "5", m: ttlUnlimitedPropMd}),
 		},
 		{
 			name:    "from non-existing; insert with multiple-metadata",
@@ -279,17 +353,17 @@ func TestNewMapWithMetadata(t *testing.T) {
 				Insert(k5, "5", WithTTL(TTLNoPropagation), WithTTL(TTLUnlimitedPropagation)),
 			},
 			want: makeTestTagMapWithMetadata(
-				tagContent{"5", ttlUnlimitedPropMd}),
+				tagContent{value: "5", m: ttlUnlimitedPropMd}),
 		},
 		{
 			name: "from existing; update with multiple-metadata",
 			initial: makeTestTagMapWithMetadata(
-				tagContent{"5", ttlNoPropMd}),
+				tagContent{value: "5", m: ttlNoPropMd}),
 			mods: []Mutator{
 				Update(k5, "5", WithTTL(TTLNoPropagation), WithTTL(TTLUnlimitedPropagation)),
 			},
 			want: makeTestTagMapWithMetadata(
-				tagContent{"5", ttlUnlimitedPropMd}),
+				tagContent{value: "5", m: ttlUnlimitedPropMd}),
 		},
 		{
 			name:    "from empty; update invalid",
@@ -332,17 +406,17 @@ func TestNewValidation(t *testing.T) {
 		seed *Map
 	}{
 		// Key name validation in seed
-		{err: "invalid key", seed: &Map{m: map[Key]tagContent{{name: ""}: {"foo", ttlNoPropMd}}}},
-		{err: "", seed: &Map{m: map[Key]tagContent{{name: "key"}: {"foo", ttlNoPropMd}}}},
-		{err: "", seed: &Map{m: map[Key]tagContent{{name: strings.Repeat("a", 255)}: {"census", ttlNoPropMd}}}},
-		{err: "invalid key", seed: &Map{m: map[Key]tagContent{{name: strings.Repeat("a", 256)}: {"census", ttlNoPropMd}}}},
-		{err: "invalid key", seed: &Map{m: map[Key]tagContent{{name: "Приве́т"}: {"census", ttlNoPropMd}}}},
+		{err: "invalid key", seed: &Map{m: map[Key]tagContent{{name: ""}: {value: "foo", m: ttlNoPropMd}}}},
+		{err: "", seed: &Map{m: map[Key]tagContent{{name: "key"}: {value: "foo", m: ttlNoPropMd}}}},
+		{err: "", seed: &Map{m: map[Key]tagContent{{name: strings.Repeat("a", 255)}: {value: "census", m: ttlNoPropMd}}}},
+		{err: "invalid key", seed: &Map{m: map[Key]tagContent{{name: strings.Repeat("a", 256)}: {value: "census", m: ttlNoPropMd}}}},
+		{err: "invalid key", seed: &Map{m: map[Key]tagContent{{name: "Приве́т"}: {value: "census", m: ttlNoPropMd}}}},
 
 		// Value validation
-		{err: "", seed: &Map{m: map[Key]tagContent{{name: "key"}: {"", ttlNoPropMd}}}},
-		{err: "", seed: &Map{m: map[Key]tagContent{{name: "key"}: {strings.Repeat("a", 255), ttlNoPropMd}}}},
-		{err: "invalid value", seed: &Map{m: map[Key]tagContent{{name: "key"}: {"Приве́т", ttlNoPropMd}}}},
-		{err: "invalid value", seed: &Map{m: map[Key]tagContent{{name: "key"}: {strings.Repeat("a", 256), ttlNoPropMd}}}},
+		{err: "", seed: &Map{m: map[Key]tagContent{{name: "key"}: {value: "", m: ttlNoPropMd}}}},
+		{err: "", seed: &Map{m: map[Key]tagContent{{name: "key"}: {value: strings.Repeat("a", 255), m: ttlNoPropMd}}}},
+		{err: "invalid value", seed: &Map{m: map[Key]tagContent{{name: "key"}: {value: "Приве́т", m: ttlNoPropMd}}}},
+		{err: "invalid value", seed: &Map{m: map[Key]tagContent{{name: "key"}: {value: strings.Repeat("a", 256), m: ttlNoPropMd}}}},
 	}
 
 	for i, tt := range tests {
@@ -374,7 +448,7 @@ func makeTestTagMap(ids ...int) *Map {
 	m := newMap()
 	for _, v := range ids {
 		k, _ := NewKey(fmt.Sprintf("k%d", v))
-		m.m[k] = tagContent{fmt.Sprintf("v%d", v), ttlUnlimitedPropMd}
+		m.m[k] = tagContent{value: fmt.Sprintf("v%d", v), m: ttlUnlimitedPropMd}
 	}
 	return m
 }
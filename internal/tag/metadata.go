@@ -41,6 +41,17 @@ var (
 	TTLNoPropagation = TTL{ttl: valueTTLNoPropagation}
 )
 
+// NewTTL returns TTL metadata that allows a tag to propagate for the provided
+// number of hops. Each hop decrements the number of hops left when decoding
+// the tag; once none are left, the tag is no longer propagated. A non-positive
+// number of hops prevents the tag from propagating.
+func NewTTL(hops int) TTL {
+	if hops <= 0 {
+		return TTLNoPropagation
+	}
+	return TTL{ttl: hops}
+}
+
 type metadatas struct {
 	ttl TTL
 }
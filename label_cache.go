@@ -0,0 +1,198 @@
+// Copyright (c) Tetrate, Inc 2023.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opentelemetry
+
+import (
+	"sync"
+
+	"github.com/tetratelabs/telemetry"
+
+	"github.com/tetratelabs/telemetry-opentelemetry/internal/tag"
+)
+
+// defaultLabelCacheSize is the default size of the label caches.
+const defaultLabelCacheSize = 1024
+
+// maxCachedLabelValues is the maximum number of label values passed to With
+// for its result to be cached.
+const maxCachedLabelValues = 4
+
+// WithLabelCacheSize sets the maximum number of label value combinations each
+// metric caches the result of With for, as well as the maximum number of values
+// interned per label. Repeated calls to With with cached combinations of up to
+// 4 label values return the previously bound metric without allocating. When
+// With is called through the telemetry.Metric interface, Go allocates the
+// variadic argument at the call site; pass a prebuilt slice, e.g.
+// m.With(lvs...), to avoid it on hot paths.
+// A size of 0 disables caching. Defaults to 1024.
+func WithLabelCacheSize(size int) SinkOption {
+	return func(ms *metricSink) {
+		ms.labelCacheSize = size
+	}
+}
+
+// labelOp is the operation a labelValue applies to a tag.Map.
+type labelOp uint8
+
+const (
+	labelInsert labelOp = iota
+	labelUpdate
+	labelUpsert
+	labelDelete
+)
+
+// labelValue is the telemetry.LabelValue created by labelImpl. Interned label
+// values are compared by pointer to look up the label caches.
+type labelValue struct {
+	tag.Mutator
+	interned bool
+}
+
+// labelValueKey identifies an interned labelValue of a label.
+type labelValueKey struct {
+	op  labelOp
+	typ tag.ValueType
+	// value holds string values, num int64 values and 1 or 0 for bool values.
+	value string
+	num   int64
+}
+
+// labelValues interns the values of a label, up to size values.
+type labelValues struct {
+	key    tag.Key
+	mu     sync.RWMutex
+	size   int
+	values map[labelValueKey]*labelValue
+}
+
+func newLabelValues(key tag.Key, size int) *labelValues {
+	return &labelValues{
+		key:    key,
+		size:   size,
+		values: make(map[labelValueKey]*labelValue),
+	}
+}
+
+// get returns the interned value of the label for the operation, creating it
+// if needed. Once the size is reached, values are no longer interned.
+func (l *labelValues) get(k labelValueKey) *labelValue {
+	if l.size <= 0 {
+		return &labelValue{Mutator: l.mutator(k)}
+	}
+	l.mu.RLock()
+	lv, ok := l.values[k]
+	l.mu.RUnlock()
+	if ok {
+		return lv
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if lv, ok = l.values[k]; ok {
+		return lv
+	}
+	if len(l.values) >= l.size {
+		return &labelValue{Mutator: l.mutator(k)}
+	}
+	lv = &labelValue{Mutator: l.mutator(k), interned: true}
+	l.values[k] = lv
+	return lv
+}
+
+func (l *labelValues) mutator(k labelValueKey) tag.Mutator {
+	switch k.typ {
+	case tag.ValueTypeInt64:
+		switch k.op {
+		case labelInsert:
+			return tag.InsertInt64(l.key, k.num)
+		case labelUpdate:
+			return tag.UpdateInt64(l.key, k.num)
+		default:
+			return tag.UpsertInt64(l.key, k.num)
+		}
+	case tag.ValueTypeBool:
+		switch k.op {
+		case labelInsert:
+			return tag.InsertBool(l.key, k.num == 1)
+		case labelUpdate:
+			return tag.UpdateBool(l.key, k.num == 1)
+		default:
+			return tag.UpsertBool(l.key, k.num == 1)
+		}
+	}
+	switch k.op {
+	case labelInsert:
+		return tag.Insert(l.key, k.value)
+	case labelUpdate:
+		return tag.Update(l.key, k.value)
+	case labelUpsert:
+		return tag.Upsert(l.key, k.value)
+	default:
+		return tag.Delete(l.key)
+	}
+}
+
+// labelCacheKey identifies a combination of interned label values.
+type labelCacheKey [maxCachedLabelValues]*labelValue
+
+// labelCache caches the metrics returned by With by the label values they
+// were bound with. Once the size is reached, an arbitrary entry is evicted.
+type labelCache struct {
+	mu      sync.RWMutex
+	size    int
+	metrics map[labelCacheKey]telemetry.Metric
+}
+
+func newLabelCache(size int) *labelCache {
+	if size <= 0 {
+		return nil
+	}
+	return &labelCache{
+		size:    size,
+		metrics: make(map[labelCacheKey]telemetry.Metric),
+	}
+}
+
+// lookup returns the metric cached for the label values, if any. It reports
+// whether the metric bound with the label values can be stored with key.
+func (c *labelCache) lookup(lvs []telemetry.LabelValue) (key labelCacheKey, m telemetry.Metric, ok bool) {
+	if c == nil || len(lvs) > maxCachedLabelValues {
+		return key, nil, false
+	}
+	for i, v := range lvs {
+		lv, isLabelValue := v.(*labelValue)
+		if !isLabelValue || !lv.interned {
+			return key, nil, false
+		}
+		key[i] = lv
+	}
+	c.mu.RLock()
+	m = c.metrics[key]
+	c.mu.RUnlock()
+	return key, m, true
+}
+
+// store caches m, the metric bound with the label values identified by key.
+func (c *labelCache) store(key labelCacheKey, m telemetry.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.metrics[key]; !ok && len(c.metrics) >= c.size {
+		for k := range c.metrics {
+			delete(c.metrics, k)
+			break
+		}
+	}
+	c.metrics[key] = m
+}
@@ -25,6 +25,7 @@ import (
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/metric"
 	sdk "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
 
 	"github.com/tetratelabs/telemetry-opentelemetry/internal/tag"
 )
@@ -34,6 +35,27 @@ var log = scope.Register("telemetry-otel", "Messages from the telemetry-OTel met
 type MetricAndDerivedMetricSink interface {
 	telemetry.MetricSink
 	telemetry.DerivedMetricSink
+
+	// NewUpDownSum creates a new Metric with an aggregation type of Sum
+	// which accepts both positive and negative values.
+	NewUpDownSum(name, description string, opts ...telemetry.MetricOption) telemetry.Metric
+
+	// NewDerivedGaugeWithOptions creates a new DerivedMetric like
+	// NewDerivedGauge, taking into account the provided MetricOptions.
+	NewDerivedGaugeWithOptions(name, description string, opts ...telemetry.MetricOption) telemetry.DerivedMetric
+
+	// RegisterRecordHook adds a RecordHook for the metric with the provided
+	// name.
+	RegisterRecordHook(name string, hook RecordHook)
+
+	// SetEnabled turns the named metric on or off at runtime. It reports
+	// whether the metric is known.
+	SetEnabled(name string, enabled bool) bool
+
+	// Namespaced returns a child sink prefixing the names of the metrics it
+	// creates with the provided namespace, nested within the namespace of
+	// the sink.
+	Namespaced(namespace string) MetricAndDerivedMetricSink
 }
 
 type SinkOption func(ms *metricSink)
@@ -44,6 +66,41 @@ func WithStrictDimensions() SinkOption {
 	}
 }
 
+// WithDefaultCardinalityLimit sets the default maximum number of label sets
+// each metric created by the sink will record. It is a per-metric limit:
+// Individual metrics can override it with WithCardinalityLimit. Recordings for
+// label sets beyond the limit are routed into a single series labeled
+// otel.metric.overflow="true" and counted by the otel_metric_overflow_total
+// metric.
+func WithDefaultCardinalityLimit(limit int) SinkOption {
+	return func(ms *metricSink) {
+		ms.cardinalityLimit = limit
+	}
+}
+
+// WithSinkCardinalityLimit sets the maximum number of label sets recorded by
+// all metrics of the sink and its namespaced children together. It applies on
+// top of the per-metric limits; once reached, recordings for new label sets of
+// any metric are routed into the overflow series of that metric. Slots of
+// deleted series are released. A limit of 0 disables the limit.
+func WithSinkCardinalityLimit(limit int) SinkOption {
+	return func(ms *metricSink) {
+		ms.cardinalityBudget = nil
+		if limit > 0 {
+			ms.cardinalityBudget = &cardinalityBudget{limit: limit}
+		}
+	}
+}
+
+// WithNamespace prefixes the names of all metrics created by the sink with
+// the provided namespace, separated by an underscore. Use it to avoid
+// collisions between libraries sharing a MeterProvider.
+func WithNamespace(namespace string) SinkOption {
+	return func(ms *metricSink) {
+		ms.namespace = namespace
+	}
+}
+
 func WithLogger(l telemetry.Logger) SinkOption {
 	return func(ms *metricSink) {
 		if l == nil {
@@ -54,42 +111,114 @@ func WithLogger(l telemetry.Logger) SinkOption {
 	}
 }
 
+// WithMeterProvider sets the MeterProvider used by the sink to create its
+// instruments. By default, the global MeterProvider is used. Use it together
+// with the exporter variants which do not install their MeterProvider globally
+// to run multiple isolated sinks in a single process. If mp is a
+// *MeterProvider, the sink is attached to it.
+func WithMeterProvider(mp metric.MeterProvider) SinkOption {
+	return func(ms *metricSink) {
+		ms.meterProvider = mp
+	}
+}
+
 // New returns a new Telemetry facade compatible MetricSink.
 func New(appName string, opts ...SinkOption) MetricAndDerivedMetricSink {
 	ms := &metricSink{
-		meter: otel.GetMeterProvider().Meter(appName),
+		appName:        appName,
+		logger:         log,
+		labelCacheSize: defaultLabelCacheSize,
 		knownMetrics: &metrics{
 			known: map[string]MetricDefinition{},
 		},
+		recordHooks: &recordHooks{},
+		overflow:    &overflowCounter{},
 	}
 	for _, opt := range opts {
 		opt(ms)
 	}
+	if ms.meterProvider == nil {
+		ms.meterProvider = otel.GetMeterProvider()
+	}
+	if mp, ok := ms.meterProvider.(*MeterProvider); ok {
+		mp.Attach(ms)
+	}
+	ms.meter = ms.meterProvider.Meter(appName)
 	return ms
 }
 
 // Start should be used by telemetry Exporters so we can trigger final
-// initialization of our histograms.
+// initialization of our histograms, exemplars and resource.
 func Start(ms telemetry.MetricSink) []sdk.Option {
 	if m, ok := ms.(*metricSink); ok {
-		return m.knownMetrics.toHistogramViews()
+		opts := m.knownMetrics.toHistogramViews()
+		if f := m.exemplarFilter.toSDK(); f != nil {
+			opts = append(opts, sdk.WithExemplarFilter(f))
+		}
+		if res := m.toResource(); res != nil {
+			opts = append(opts, sdk.WithResource(res))
+		}
+		return opts
 	}
 
 	return nil
 }
 
 type metricSink struct {
+	appName          string
 	logger           telemetry.Logger
+	meterProvider    metric.MeterProvider
 	meter            metric.Meter
 	knownMetrics     *metrics
+	recordHooks      *recordHooks
 	strictDimensions bool
+	cardinalityLimit int
+	// cardinalityBudget is shared with namespaced children, nil if unlimited.
+	cardinalityBudget *cardinalityBudget
+	exemplarFilter    ExemplarFilter
+	resource          *resource.Resource
+	resourceFromEnv   bool
+	// namespace prefixes the names of all metrics created by the sink.
+	namespace      string
+	nameValidation NameValidation
+	labelCacheSize int
+	overflow       *overflowCounter
+}
+
+// Namespaced returns a child sink sharing the configuration, metric
+// definitions and record hooks of the sink, which prefixes the names of the
+// metrics it creates with namespace. Names passed to the child, including those
+// of SetEnabled and RegisterRecordHook, are relative to its namespace.
+func (m *metricSink) Namespaced(namespace string) MetricAndDerivedMetricSink {
+	child := *m
+	child.namespace = m.fullName(namespace)
+	return &child
+}
+
+// fullName prefixes name with the namespace of the sink.
+func (m *metricSink) fullName(name string) string {
+	if m.namespace == "" {
+		return name
+	}
+	return m.namespace + "_" + name
 }
 
 // NewLabel creates a new Label to be used as a metrics dimension.
+// Depending on the NameValidation of the sink, invalid names are sanitized or
+// rejected; values of rejected labels are ignored.
 func (m *metricSink) NewLabel(name string) telemetry.Label {
-	label, _ := tag.NewKey(name)
+	name, ok := m.validateLabelName(name)
+	if !ok {
+		return noopLabel{}
+	}
+	label, err := tag.NewKey(name)
+	if err != nil {
+		m.logger.Error("rejected label", err, "label", name)
+		return noopLabel{}
+	}
 	return &labelImpl{
-		label: label,
+		label:  label,
+		values: newLabelValues(label, m.labelCacheSize),
 	}
 }
 
@@ -108,26 +237,99 @@ func (m *metricSink) ContextWithLabels(ctx context.Context, values ...telemetry.
 	return tag.New(ctx, mutators...)
 }
 
+// TypedLabel is implemented by the labels created by the sink. Besides string
+// values, it accepts int64 and bool values, which are recorded as typed
+// attributes and propagated with their type by the binary tag codec.
+//
+//	if l, ok := ms.NewLabel("retries").(opentelemetry.TypedLabel); ok {
+//		m = m.With(l.InsertInt64(3))
+//	}
+type TypedLabel interface {
+	telemetry.Label
+
+	// InsertInt64 will insert the provided int64 value for the Label if not
+	// set.
+	InsertInt64(value int64) telemetry.LabelValue
+
+	// UpdateInt64 will update the Label with provided int64 value if already
+	// set.
+	UpdateInt64(value int64) telemetry.LabelValue
+
+	// UpsertInt64 will insert or replace the provided int64 value for the
+	// Label.
+	UpsertInt64(value int64) telemetry.LabelValue
+
+	// InsertBool will insert the provided bool value for the Label if not set.
+	InsertBool(value bool) telemetry.LabelValue
+
+	// UpdateBool will update the Label with provided bool value if already
+	// set.
+	UpdateBool(value bool) telemetry.LabelValue
+
+	// UpsertBool will insert or replace the provided bool value for the Label.
+	UpsertBool(value bool) telemetry.LabelValue
+}
+
 type labelImpl struct {
-	label tag.Key
+	label  tag.Key
+	values *labelValues
 }
 
+var _ TypedLabel = (*labelImpl)(nil)
+
 // Insert will insert the provided value for the Label if not set.
 func (l labelImpl) Insert(val string) telemetry.LabelValue {
-	return tag.Insert(l.label, val)
+	return l.values.get(labelValueKey{op: labelInsert, value: val})
 }
 
 // Update will update the Label with provided value if already set.
 func (l labelImpl) Update(val string) telemetry.LabelValue {
-	return tag.Update(l.label, val)
+	return l.values.get(labelValueKey{op: labelUpdate, value: val})
 }
 
 // Upsert will insert or replace the provided value for the Label.
 func (l labelImpl) Upsert(val string) telemetry.LabelValue {
-	return tag.Upsert(l.label, val)
+	return l.values.get(labelValueKey{op: labelUpsert, value: val})
 }
 
 // Delete will remove the Label's value.
 func (l labelImpl) Delete() telemetry.LabelValue {
-	return tag.Delete(l.label)
+	return l.values.get(labelValueKey{op: labelDelete})
+}
+
+// InsertInt64 will insert the provided int64 value for the Label if not set.
+func (l labelImpl) InsertInt64(val int64) telemetry.LabelValue {
+	return l.values.get(labelValueKey{op: labelInsert, typ: tag.ValueTypeInt64, num: val})
+}
+
+// UpdateInt64 will update the Label with provided int64 value if already set.
+func (l labelImpl) UpdateInt64(val int64) telemetry.LabelValue {
+	return l.values.get(labelValueKey{op: labelUpdate, typ: tag.ValueTypeInt64, num: val})
+}
+
+// UpsertInt64 will insert or replace the provided int64 value for the Label.
+func (l labelImpl) UpsertInt64(val int64) telemetry.LabelValue {
+	return l.values.get(labelValueKey{op: labelUpsert, typ: tag.ValueTypeInt64, num: val})
+}
+
+// InsertBool will insert the provided bool value for the Label if not set.
+func (l labelImpl) InsertBool(val bool) telemetry.LabelValue {
+	return l.values.get(labelValueKey{op: labelInsert, typ: tag.ValueTypeBool, num: boolToInt(val)})
+}
+
+// UpdateBool will update the Label with provided bool value if already set.
+func (l labelImpl) UpdateBool(val bool) telemetry.LabelValue {
+	return l.values.get(labelValueKey{op: labelUpdate, typ: tag.ValueTypeBool, num: boolToInt(val)})
+}
+
+// UpsertBool will insert or replace the provided bool value for the Label.
+func (l labelImpl) UpsertBool(val bool) telemetry.LabelValue {
+	return l.values.get(labelValueKey{op: labelUpsert, typ: tag.ValueTypeBool, num: boolToInt(val)})
+}
+
+func boolToInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
 }
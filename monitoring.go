@@ -16,8 +16,8 @@
 package opentelemetry
 
 import (
-	"errors"
 	"sync"
+	"sync/atomic"
 
 	"github.com/tetratelabs/telemetry"
 	"go.opentelemetry.io/otel/sdk/metric"
@@ -26,34 +26,58 @@ import (
 	"github.com/tetratelabs/telemetry-opentelemetry/internal/slices"
 )
 
+// SetEnabled turns the named metric on or off at runtime, overriding the
+// EnabledCondition evaluated when it was created. Recordings on a disabled
+// metric are dropped, and its gauges are not observed. It reports whether the
+// metric is known.
+func (m *metricSink) SetEnabled(name string, enabled bool) bool {
+	return m.knownMetrics.setEnabled(m.fullName(name), enabled)
+}
+
 // NewSum creates a new Metric with an aggregation type of Sum (the values will
 // be cumulative). That means that data collected by the new Metric will be
 // summed before export.
 func (m *metricSink) NewSum(name, description string, opts ...telemetry.MetricOption) telemetry.Metric {
-	m.knownMetrics.register(MetricDefinition{
+	o := m.createOptions(opts...)
+	name, ok := m.register(MetricDefinition{
 		Name:        name,
 		Type:        "Sum",
 		Description: description,
-	})
-	o, dm := m.createOptions(name, opts...)
-	if dm != nil {
-		return dm
+	}, &o)
+	if !ok {
+		return &noopMetric{name: name}
 	}
 	return m.newCounter(name, description, o)
 }
 
+// NewUpDownSum creates a new Metric with an aggregation type of Sum which, unlike
+// NewSum, accepts negative values. Use it for values that go up and down, such
+// as queue depths or in-flight requests. The Metric is exported as a gauge.
+func (m *metricSink) NewUpDownSum(name, description string, opts ...telemetry.MetricOption) telemetry.Metric {
+	o := m.createOptions(opts...)
+	name, ok := m.register(MetricDefinition{
+		Name:        name,
+		Type:        "UpDownSum",
+		Description: description,
+	}, &o)
+	if !ok {
+		return &noopMetric{name: name}
+	}
+	return m.newUpDownCounter(name, description, o)
+}
+
 // NewGauge creates a new Metric with an aggregation type of LastValue. That
 // means that data collected by the new Metric will export only the last
 // recorded value.
 func (m *metricSink) NewGauge(name, description string, opts ...telemetry.MetricOption) telemetry.Metric {
-	m.knownMetrics.register(MetricDefinition{
+	o := m.createOptions(opts...)
+	name, ok := m.register(MetricDefinition{
 		Name:        name,
 		Type:        "LastValue",
 		Description: description,
-	})
-	o, dm := m.createOptions(name, opts...)
-	if dm != nil {
-		return dm
+	}, &o)
+	if !ok {
+		return &noopMetric{name: name}
 	}
 	return m.newGauge(name, description, o)
 }
@@ -62,55 +86,89 @@ func (m *metricSink) NewGauge(name, description string, opts ...telemetry.Metric
 // Metric will export only the last recorded value.
 // Unlike NewGauge, the DerivedGauge accepts functions which are called to get the current value.
 func (m *metricSink) NewDerivedGauge(name, description string) telemetry.DerivedMetric {
-	m.knownMetrics.register(MetricDefinition{
+	return m.NewDerivedGaugeWithOptions(name, description)
+}
+
+// NewDerivedGaugeWithOptions creates a new Gauge Metric like NewDerivedGauge,
+// taking into account the provided MetricOptions.
+func (m *metricSink) NewDerivedGaugeWithOptions(name, description string, opts ...telemetry.MetricOption) telemetry.DerivedMetric {
+	o := m.createOptions(opts...)
+	name, ok := m.register(MetricDefinition{
 		Name:        name,
 		Type:        "LastValue",
 		Description: description,
-	})
-	return m.newDerivedGauge(name, description)
+	}, &o)
+	if !ok {
+		return &noopMetric{name: name}
+	}
+	return m.newDerivedGauge(name, description, o)
 }
 
 // NewDistribution creates a new Metric with an aggregation type of Distribution.
 // This means that the data collected by the Metric will be collected and
 // exported as a histogram, with the specified bounds.
+// Use WithExponentialHistogram to export it as a base-2 exponential histogram
+// instead, in which case bounds are ignored.
 func (m *metricSink) NewDistribution(name, description string, bounds []float64, opts ...telemetry.MetricOption) telemetry.Metric {
-	m.knownMetrics.register(MetricDefinition{
+	o := m.createOptions(opts...)
+	def := MetricDefinition{
 		Name:        name,
 		Type:        "Distribution",
 		Description: description,
 		Bounds:      bounds,
-	})
-	o, dm := m.createOptions(name, opts...)
-	if dm != nil {
-		return dm
+		Aggregation: AggregationExplicitBucketHistogram,
 	}
-	return m.newDistribution(name, description, o)
+	if o.exponential != nil {
+		def.Aggregation = AggregationBase2ExponentialHistogram
+		def.exponential = o.exponential
+	}
+	name, ok := m.register(def, &o)
+	if !ok {
+		return &noopMetric{name: name}
+	}
+	return m.newDistribution(name, description, bounds, o)
 }
 
 // MetricDefinition records a metric's metadata.
 // This is used to work around two limitations of OpenTelemetry:
 //   - (https://github.com/open-telemetry/opentelemetry-go/issues/4003) Histogram buckets cannot be defined per instrument.
-//     instead, we record all metric definitions and add them as Views at registration time.
+//     instead, we record all metric definitions and look them up from a View at instrument creation time.
 //   - Support pkg/collateral, which wants to query all metrics. This cannot use a simple Collect() call, as this ignores any unused metrics.
 type MetricDefinition struct {
 	Name        string
 	Type        string
 	Description string
 	Bounds      []float64
+	// Aggregation holds the aggregation used to export a Distribution.
+	Aggregation string
+	// Enabled reports whether the metric currently records values.
+	Enabled bool
+
+	exponential *exponentialHistogram
+	enabled     *atomic.Bool
 }
 
+// Aggregations used to export Distributions.
+const (
+	AggregationExplicitBucketHistogram   = "ExplicitBucketHistogram"
+	AggregationBase2ExponentialHistogram = "Base2ExponentialHistogram"
+)
+
 // metrics stores known metrics
 type metrics struct {
-	started bool
-	mu      sync.Mutex
-	known   map[string]MetricDefinition
+	mu    sync.Mutex
+	known map[string]MetricDefinition
 }
 
 // ExportMetricDefinitions reports all currently registered metric definitions.
 func (m *metricSink) ExportMetricDefinitions() []MetricDefinition {
 	m.knownMetrics.mu.Lock()
 	defer m.knownMetrics.mu.Unlock()
-	return slices.SortFunc(maps.Values(m.knownMetrics.known), func(a, b MetricDefinition) int {
+	defs := maps.Values(m.knownMetrics.known)
+	for i := range defs {
+		defs[i].Enabled = defs[i].enabled.Load()
+	}
+	return slices.SortFunc(defs, func(a, b MetricDefinition) int {
 		if a.Name < b.Name {
 			return -1
 		} else if a.Name == b.Name {
@@ -121,37 +179,101 @@ func (m *metricSink) ExportMetricDefinitions() []MetricDefinition {
 	})
 }
 
-// register records a newly defined metric. Only valid before an exporter is set.
-func (d *metrics) register(def MetricDefinition) {
+// register prefixes the name of the metric with the namespace of the sink,
+// validates it and records its definition, setting the flag toggling the
+// metric in o. It returns the name to create the metric with, and false if the
+// metric is rejected.
+func (m *metricSink) register(def MetricDefinition, o *metricOptions) (string, bool) {
+	name, ok := m.validateMetricName(m.fullName(def.Name))
+	if !ok {
+		return name, false
+	}
+	def.Name = name
+	enabled, err := m.knownMetrics.register(def, o.isEnabled(), m.nameValidation == NameValidationStrict)
+	if err != nil {
+		m.logger.Error("conflicting metric registration", err, "metric", name)
+	}
+	if enabled == nil {
+		return name, false
+	}
+	o.enabled = enabled
+	return name, true
+}
+
+// register records a newly defined metric. Metrics may be registered at any
+// time, including after an exporter has started. It returns the flag toggling
+// the metric at runtime, which is shared when a metric is registered again.
+// A registration conflicting with the previous one replaces it, unless strict
+// is set, in which case no flag is returned. Either way the conflict is
+// reported as error.
+func (d *metrics) register(def MetricDefinition, enabled, strict bool) (*atomic.Bool, error) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
-	if d.started {
-		log.Error("Unable to register metric", errors.New("metrics have already started"), "metric", def.Name)
+	var err error
+	if prev, ok := d.known[def.Name]; ok {
+		if err = def.conflict(prev); err != nil && strict {
+			return nil, err
+		}
+		// keep the state set with SetEnabled since the first registration.
+		def.enabled = prev.enabled
+	} else {
+		def.enabled = &atomic.Bool{}
+		def.enabled.Store(enabled)
 	}
 	d.known[def.Name] = def
+	return def.enabled, err
+}
+
+// setEnabled toggles the named metric, reporting whether it is known.
+func (d *metrics) setEnabled(name string, enabled bool) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	def, ok := d.known[name]
+	if !ok {
+		return false
+	}
+	def.enabled.Store(enabled)
+	return true
 }
 
 // toHistogramViews works around https://github.com/open-telemetry/opentelemetry-go/issues/4003; in the future we can define
 // this when we create the histogram.
+// Rather than a static view per known histogram, a single view looks up the
+// definition when the instrument is created, so histograms registered after
+// the exporter has started keep their aggregation.
 func (d *metrics) toHistogramViews() []metric.Option {
+	return []metric.Option{metric.WithView(d.view)}
+}
+
+// view returns the stream of a Distribution instrument, selecting the
+// aggregation recorded in its definition.
+func (d *metrics) view(inst metric.Instrument) (metric.Stream, bool) {
 	d.mu.Lock()
-	defer d.mu.Unlock()
-	d.started = true
-	opts := []metric.Option{}
-	for name, def := range d.known {
-		if def.Bounds == nil {
-			continue
+	def, ok := d.known[inst.Name]
+	d.mu.Unlock()
+	if !ok || def.Type != "Distribution" {
+		return metric.Stream{}, false
+	}
+	var agg metric.Aggregation
+	switch {
+	case def.exponential != nil:
+		// exponential histograms pick their buckets based on the recorded values.
+		agg = metric.AggregationBase2ExponentialHistogram{
+			MaxSize:  def.exponential.maxSize,
+			MaxScale: def.exponential.maxScale,
+		}
+	case def.Bounds != nil:
+		// for each histogram metric (i.e. those with bounds), explicitly define those buckets.
+		agg = metric.AggregationExplicitBucketHistogram{
+			Boundaries: def.Bounds,
 		}
-		// for each histogram metric (i.e. those with bounds), set up a view explicitly defining those buckets.
-		v := metric.WithView(metric.NewView(
-			metric.Instrument{Name: name},
-			metric.Stream{
-				Aggregation: metric.AggregationExplicitBucketHistogram{
-					Boundaries: def.Bounds,
-				},
-			},
-		))
-		opts = append(opts, v)
+	default:
+		return metric.Stream{}, false
 	}
-	return opts
+	return metric.Stream{
+		Name:        inst.Name,
+		Description: inst.Description,
+		Unit:        inst.Unit,
+		Aggregation: agg,
+	}, true
 }
@@ -16,12 +16,24 @@
 package opentelemetry_test
 
 import (
+	"context"
+	"strconv"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/tetratelabs/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	sdk "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/trace"
 
 	opentelemetry "github.com/tetratelabs/telemetry-opentelemetry"
 	"github.com/tetratelabs/telemetry-opentelemetry/internal/monitortest"
+	otelprom "github.com/tetratelabs/telemetry-opentelemetry/pkg/prometheus"
 )
 
 var (
@@ -31,6 +43,9 @@ var (
 
 	testSum                telemetry.Metric
 	goofySum               telemetry.Metric
+	testUpDownSum          telemetry.Metric
+	testLimitedSum         telemetry.Metric
+	testSinkLimitedSum     telemetry.Metric
 	hookSum                telemetry.Metric
 	testDistribution       telemetry.Metric
 	testGauge              telemetry.Metric
@@ -41,7 +56,7 @@ var (
 )
 
 func init() {
-	ms = opentelemetry.New("test")
+	ms = opentelemetry.New("test", opentelemetry.WithMeterProvider(monitortest.MeterProvider()))
 	telemetry.SetGlobalMetricSink(ms)
 
 	name = ms.NewLabel("name")
@@ -54,6 +69,25 @@ func init() {
 
 	goofySum = testSum.With(kind.Upsert("goofy"))
 
+	testUpDownSum = ms.NewUpDownSum(
+		"events_in_flight",
+		"Number of events in flight, by kind",
+	)
+
+	testLimitedSum = ms.NewSum(
+		"events_limited_total",
+		"Number of events observed, by name",
+		opentelemetry.WithCardinalityLimit(2),
+	)
+
+	testSinkLimitedSum = opentelemetry.New("test",
+		opentelemetry.WithMeterProvider(monitortest.MeterProvider()),
+		opentelemetry.WithDefaultCardinalityLimit(1),
+	).NewSum(
+		"events_sink_limited_total",
+		"Number of events observed, by name",
+	)
+
 	hookSum = ms.NewSum(
 		"hook_total",
 		"Number of hook events observed",
@@ -94,8 +128,6 @@ func init() {
 		"test_derived_gauge_labels",
 		"Testing derived gauge functionality",
 	)
-
-	monitortest.SetSink(ms)
 }
 
 func TestMonitorTestReset(t *testing.T) {
@@ -124,6 +156,97 @@ func TestSum(t *testing.T) {
 	mt.Assert(testSum.Name(), map[string]string{"kind": "bar"}, monitortest.Exactly(1))
 }
 
+func TestUpDownSum(t *testing.T) {
+	mt := monitortest.New(t)
+
+	testUpDownSum.With(kind.Upsert("queued")).Record(5)
+	testUpDownSum.With(kind.Upsert("queued")).Decrement()
+	testUpDownSum.With(kind.Upsert("queued")).Record(-2)
+	testUpDownSum.With(kind.Upsert("done")).Decrement()
+
+	mt.Assert(testUpDownSum.Name(), map[string]string{"kind": "queued"}, monitortest.Exactly(2))
+	mt.Assert(testUpDownSum.Name(), map[string]string{"kind": "done"}, monitortest.Exactly(-1))
+}
+
+func TestCardinalityLimit(t *testing.T) {
+	mt := monitortest.New(t)
+
+	testLimitedSum.With(name.Upsert("a")).Increment()
+	testLimitedSum.With(name.Upsert("b")).Increment()
+	testLimitedSum.With(name.Upsert("c")).Increment()
+	testLimitedSum.With(name.Upsert("d")).Record(2)
+	testLimitedSum.With(name.Upsert("c")).Increment()
+	testLimitedSum.With(name.Upsert("a")).Increment()
+
+	mt.Assert(testLimitedSum.Name(), map[string]string{"name": "a"}, monitortest.Exactly(2))
+	mt.Assert(testLimitedSum.Name(), map[string]string{"name": "b"}, monitortest.Exactly(1))
+	mt.Assert(testLimitedSum.Name(), map[string]string{"otel.metric.overflow": "true"}, monitortest.Exactly(4))
+	// each recording of c and d is counted.
+	mt.Assert("otel_metric_overflow_total", map[string]string{"metric": testLimitedSum.Name()}, monitortest.Exactly(3))
+
+	testSinkLimitedSum.With(name.Upsert("a")).Increment()
+	testSinkLimitedSum.With(name.Upsert("b")).Increment()
+
+	mt.Assert(testSinkLimitedSum.Name(), map[string]string{"name": "a"}, monitortest.Exactly(1))
+	mt.Assert(testSinkLimitedSum.Name(), map[string]string{"otel.metric.overflow": "true"}, monitortest.Exactly(1))
+}
+
+func TestSinkCardinalityLimit(t *testing.T) {
+	mt := monitortest.New(t)
+
+	sink := opentelemetry.New("test",
+		opentelemetry.WithMeterProvider(monitortest.MeterProvider()),
+		opentelemetry.WithSinkCardinalityLimit(3),
+	)
+	label := sink.NewLabel("name")
+	sum := sink.NewSum("sink_budget_total", "Sharing the sink cardinality limit")
+	g := sink.Namespaced("child").NewGauge("sink_budget", "Sharing the sink cardinality limit")
+
+	// label sets of all metrics, including namespaced ones, share the limit.
+	sum.With(label.Upsert("a")).Increment()
+	sum.With(label.Upsert("b")).Increment()
+	g.With(label.Upsert("a")).Record(1)
+	g.With(label.Upsert("b")).Record(2)
+	sum.With(label.Upsert("c")).Increment()
+
+	mt.Assert(sum.Name(), map[string]string{"name": "a"}, monitortest.Exactly(1))
+	mt.Assert(sum.Name(), map[string]string{"name": "b"}, monitortest.Exactly(1))
+	mt.Assert(sum.Name(), map[string]string{"otel.metric.overflow": "true"}, monitortest.Exactly(1))
+	mt.Assert(g.Name(), map[string]string{"name": "a"}, monitortest.Exactly(1))
+	mt.Assert(g.Name(), map[string]string{"otel.metric.overflow": "true"}, monitortest.Exactly(2))
+
+	// deleted series release their slot to every metric of the sink.
+	if !g.(opentelemetry.Deleter).Delete(label.Upsert("a")) {
+		t.Fatal("expected series to be deleted")
+	}
+	sum.With(label.Upsert("c")).Increment()
+	mt.Assert(sum.Name(), map[string]string{"name": "c"}, monitortest.Exactly(1))
+}
+
+func TestDisabledOverflowCounter(t *testing.T) {
+	mt := monitortest.New(t)
+
+	sink := opentelemetry.New("test",
+		opentelemetry.WithMeterProvider(monitortest.MeterProvider()),
+		opentelemetry.WithDefaultCardinalityLimit(1),
+	)
+	label := sink.NewLabel("name")
+	sum := sink.NewSum("events_overflow_toggled_total", "Number of events observed, by name")
+
+	// the counter can be toggled before the first overflow.
+	if !sink.SetEnabled("otel_metric_overflow_total", false) {
+		t.Fatal("want otel_metric_overflow_total known")
+	}
+	sum.With(label.Upsert("a")).Increment()
+	sum.With(label.Upsert("b")).Increment()
+	mt.Assert(sum.Name(), map[string]string{"otel.metric.overflow": "true"}, monitortest.Exactly(1))
+	mt.Assert("otel_metric_overflow_total", map[string]string{"metric": sum.Name()}, monitortest.DoesNotExist)
+
+	sink.SetEnabled("otel_metric_overflow_total", true)
+	sum.With(label.Upsert("c")).Increment()
+	mt.Assert("otel_metric_overflow_total", map[string]string{"metric": sum.Name()}, monitortest.Exactly(1))
+}
+
 func TestRegisterIfSum(t *testing.T) {
 	mt := monitortest.New(t)
 
@@ -134,6 +257,235 @@ func TestRegisterIfSum(t *testing.T) {
 	mt.Assert(testConditionalSum.Name(), map[string]string{"name": "foo", "kind": "bar"}, monitortest.Exactly(1))
 }
 
+func TestSetEnabled(t *testing.T) {
+	mt := monitortest.New(t)
+
+	sum := ms.NewSum("events_toggled_total", "Number of events observed, while enabled",
+		telemetry.WithEnabled(func() bool { return false }))
+	g := ms.NewGauge("toggled_gauge", "Gauge observed while enabled").With(name.Upsert("foo"))
+	enabled := func(name string) bool {
+		for _, def := range ms.(interface {
+			ExportMetricDefinitions() []opentelemetry.MetricDefinition
+		}).ExportMetricDefinitions() {
+			if def.Name == name {
+				return def.Enabled
+			}
+		}
+		t.Fatalf("%s not found", name)
+		return false
+	}
+
+	sum.Increment()
+	mt.Assert(sum.Name(), nil, monitortest.DoesNotExist)
+	if enabled(sum.Name()) {
+		t.Errorf("want %s disabled", sum.Name())
+	}
+
+	if !ms.SetEnabled(sum.Name(), true) {
+		t.Fatalf("want %s known", sum.Name())
+	}
+	sum.With(name.Upsert("foo")).Increment()
+	mt.Assert(sum.Name(), map[string]string{"name": "foo"}, monitortest.Exactly(1))
+	if !enabled(sum.Name()) {
+		t.Errorf("want %s enabled", sum.Name())
+	}
+
+	g.Record(3)
+	mt.Assert(g.Name(), map[string]string{"name": "foo"}, monitortest.Exactly(3))
+	ms.SetEnabled(g.Name(), false)
+	g.Record(5)
+	mt.Assert(g.Name(), nil, monitortest.DoesNotExist)
+	ms.SetEnabled(g.Name(), true)
+	mt.Assert(g.Name(), map[string]string{"name": "foo"}, monitortest.Exactly(3))
+
+	// registering the metric again keeps it disabled.
+	ms.SetEnabled(sum.Name(), false)
+	again := ms.NewSum("events_toggled_total", "Number of events observed, while enabled")
+	if enabled(sum.Name()) {
+		t.Errorf("want %s disabled after registering it again", sum.Name())
+	}
+	again.With(name.Upsert("bar")).Increment()
+	mt.Assert(sum.Name(), map[string]string{"name": "bar"}, monitortest.DoesNotExist)
+
+	if ms.SetEnabled("unknown_metric", true) {
+		t.Error("want unknown_metric not found")
+	}
+}
+
+func TestNamespace(t *testing.T) {
+	mt := monitortest.New(t)
+
+	sink := opentelemetry.New("namespace-test",
+		opentelemetry.WithMeterProvider(monitortest.MeterProvider()),
+		opentelemetry.WithNamespace("tsb"))
+	child := sink.Namespaced("plugin")
+
+	requests := sink.NewSum("requests_total", "Number of requests")
+	childRequests := child.NewSum("requests_total", "Number of plugin requests")
+	if requests.Name() != "tsb_requests_total" {
+		t.Errorf("want tsb_requests_total, got %s", requests.Name())
+	}
+	if childRequests.Name() != "tsb_plugin_requests_total" {
+		t.Errorf("want tsb_plugin_requests_total, got %s", childRequests.Name())
+	}
+
+	requests.Increment()
+	childRequests.Record(2)
+	mt.Assert("tsb_requests_total", nil, monitortest.Exactly(1))
+	mt.Assert("tsb_plugin_requests_total", nil, monitortest.Exactly(2))
+
+	// the child shares definitions and resolves names relative to its namespace.
+	child.SetEnabled("requests_total", false)
+	childRequests.Increment()
+	mt.Assert("tsb_plugin_requests_total", nil, monitortest.Exactly(2))
+
+	var names []string
+	for _, def := range sink.(interface {
+		ExportMetricDefinitions() []opentelemetry.MetricDefinition
+	}).ExportMetricDefinitions() {
+		names = append(names, def.Name)
+	}
+	if len(names) != 2 || names[0] != "tsb_plugin_requests_total" || names[1] != "tsb_requests_total" {
+		t.Errorf("want namespaced definitions, got %v", names)
+	}
+}
+
+// errorLogger records the errors logged by a sink.
+type errorLogger struct {
+	telemetry.Logger
+	errs []error
+}
+
+func (l *errorLogger) Debug(string, ...interface{}) {}
+
+func (l *errorLogger) Error(_ string, err error, _ ...interface{}) {
+	l.errs = append(l.errs, err)
+}
+
+func TestNameValidation(t *testing.T) {
+	newSink := func(v opentelemetry.NameValidation) (opentelemetry.MetricAndDerivedMetricSink, *errorLogger) {
+		logger := &errorLogger{}
+		return opentelemetry.New("validation-test",
+			opentelemetry.WithMeterProvider(monitortest.MeterProvider()),
+			opentelemetry.WithLogger(logger),
+			opentelemetry.WithNameValidation(v),
+		), logger
+	}
+	definition := func(t *testing.T, sink opentelemetry.MetricAndDerivedMetricSink, name string) opentelemetry.MetricDefinition {
+		t.Helper()
+		for _, def := range sink.(interface {
+			ExportMetricDefinitions() []opentelemetry.MetricDefinition
+		}).ExportMetricDefinitions() {
+			if def.Name == name {
+				return def
+			}
+		}
+		t.Fatalf("%s not found", name)
+		return opentelemetry.MetricDefinition{}
+	}
+
+	t.Run("lenient", func(t *testing.T) {
+		mt := monitortest.New(t)
+		sink, logger := newSink(opentelemetry.NameValidationLenient)
+
+		sink.NewSum("lenient.total", "Accepted as is").Increment()
+		mt.Assert("lenient.total", nil, monitortest.Exactly(1))
+
+		// conflicting registrations are reported and replace the definition.
+		sink.NewGauge("lenient.total", "Registered again")
+		if len(logger.errs) != 1 {
+			t.Fatalf("want 1 error, got %v", logger.errs)
+		}
+		if got := definition(t, sink, "lenient.total").Type; got != "LastValue" {
+			t.Errorf("want LastValue, got %s", got)
+		}
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		mt := monitortest.New(t)
+		sink, logger := newSink(opentelemetry.NameValidationStrict)
+
+		sink.NewSum("strict-rejected_total", "Rejected").Increment()
+		mt.Assert("strict_rejected_total", nil, monitortest.DoesNotExist)
+
+		rejected := sink.NewLabel("rejected label")
+		sum := sink.NewSum("strict_total", "Accepted")
+		sum.With(rejected.Insert("value")).Increment()
+		mt.Assert("strict_total", nil, monitortest.Exactly(1))
+
+		sink.NewDistribution("strict_latency", "Latency", []float64{1, 2})
+		sink.NewDistribution("strict_latency", "Latency", []float64{1, 5}).Record(3)
+		sink.NewGauge("strict_total", "Registered again").Record(5)
+		mt.Assert("strict_total", nil, monitortest.Exactly(1))
+		mt.Assert("strict_latency", nil, monitortest.DoesNotExist)
+		if got := definition(t, sink, "strict_total").Type; got != "Sum" {
+			t.Errorf("want Sum, got %s", got)
+		}
+		if len(logger.errs) != 4 {
+			t.Fatalf("want 4 errors, got %v", logger.errs)
+		}
+	})
+
+	t.Run("sanitize", func(t *testing.T) {
+		mt := monitortest.New(t)
+		sink, logger := newSink(opentelemetry.NameValidationSanitize)
+
+		method := sink.NewLabel("http.method")
+		sum := sink.NewSum("sanitized.requests-total", "Sanitized")
+		if sum.Name() != "sanitized_requests_total" {
+			t.Errorf("want sanitized_requests_total, got %s", sum.Name())
+		}
+		sum.With(method.Insert("GET")).Increment()
+		mt.Assert("sanitized_requests_total", map[string]string{"http_method": "GET"}, monitortest.Exactly(1))
+
+		sink.NewSum("1st_total", "Cannot be sanitized")
+		if len(logger.errs) != 1 {
+			t.Fatalf("want 1 error, got %v", logger.errs)
+		}
+	})
+}
+
+func TestLabelCache(t *testing.T) {
+	mt := monitortest.New(t)
+
+	if testSum.With(name.Upsert("cached")) != testSum.With(name.Upsert("cached")) {
+		t.Error("want cached metric for repeated label values")
+	}
+	if testSum.With(name.Upsert("cached")) == testSum.With(name.Insert("cached")) {
+		t.Error("want distinct metrics for distinct label operations")
+	}
+
+	// a prebuilt slice avoids the allocation of the variadic argument.
+	lvs := []telemetry.LabelValue{name.Upsert("cached"), kind.Upsert("cached")}
+	for _, m := range []telemetry.Metric{testSum, testUpDownSum, testDistribution, testGauge} {
+		m.With(lvs...)
+		if n := testing.AllocsPerRun(100, func() { m.With(lvs...) }); n != 0 {
+			t.Errorf("want no allocations for cached %s label values, got %v", m.Name(), n)
+		}
+	}
+
+	small := opentelemetry.New("test",
+		opentelemetry.WithMeterProvider(monitortest.MeterProvider()),
+		opentelemetry.WithLabelCacheSize(1),
+	)
+	label := small.NewLabel("name")
+	sum := small.NewSum("events_small_cache_total", "Number of events observed, with a small label cache")
+	a := sum.With(label.Upsert("a"))
+	if sum.With(label.Upsert("a")) != a {
+		t.Error("want cached metric for repeated label values")
+	}
+	// b is not interned as the label is full, nor cached.
+	if sum.With(label.Upsert("b")) == sum.With(label.Upsert("b")) {
+		t.Error("want no caching beyond the cache size")
+	}
+
+	a.Increment()
+	sum.With(label.Upsert("a")).Increment()
+	sum.With(label.Upsert("b")).Increment()
+	mt.Assert(sum.Name(), map[string]string{"name": "a"}, monitortest.Exactly(2))
+	mt.Assert(sum.Name(), map[string]string{"name": "b"}, monitortest.Exactly(1))
+}
+
 func TestGauge(t *testing.T) {
 	mt := monitortest.New(t)
 
@@ -154,13 +506,166 @@ func TestGaugeLabels(t *testing.T) {
 	mt.Assert(testGauge.Name(), map[string]string{"kind": "bar"}, monitortest.Exactly(72))
 }
 
+func TestGaugeContextLabels(t *testing.T) {
+	mt := monitortest.New(t)
+
+	ctx, err := ms.ContextWithLabels(context.Background(), kind.Upsert("ctx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testGauge.RecordContext(ctx, 3)
+	ctx, err = ms.ContextWithLabels(context.Background(), kind.Upsert("ctx-with"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testGauge.With(name.Upsert("with")).RecordContext(ctx, 5)
+	testGauge.With(name.Upsert("with")).RecordContext(ctx, 6)
+
+	mt.Assert(testGauge.Name(), map[string]string{"kind": "ctx"}, monitortest.Exactly(3))
+	mt.Assert(testGauge.Name(), map[string]string{"kind": "ctx-with", "name": "with"}, monitortest.Exactly(6))
+
+	strict := opentelemetry.New("test",
+		opentelemetry.WithMeterProvider(monitortest.MeterProvider()),
+		opentelemetry.WithStrictDimensions(),
+	)
+	g := strict.NewGauge("test_gauge_strict", "Testing gauge strict dimensions",
+		telemetry.WithLabels(kind))
+	ctx, err = strict.ContextWithLabels(context.Background(), kind.Upsert("strict"), name.Upsert("dropped"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.RecordContext(ctx, 42)
+	mt.Assert(g.Name(), map[string]string{"kind": "strict"}, monitortest.Exactly(42))
+	mt.Assert(g.Name(), map[string]string{"name": "dropped"}, monitortest.DoesNotExist)
+}
+
+func TestGaugeDelete(t *testing.T) {
+	mt := monitortest.New(t)
+
+	testGauge.With(kind.Upsert("stale"), name.Upsert("a")).Record(1)
+	testGauge.With(kind.Upsert("stale"), name.Upsert("b")).Record(2)
+	testGauge.With(kind.Upsert("live"), name.Upsert("a")).Record(3)
+
+	d := testGauge.With(kind.Upsert("live"), name.Upsert("a")).(opentelemetry.Deleter)
+	if !d.Delete() {
+		t.Fatal("expected series to be deleted")
+	}
+	if d.Delete() {
+		t.Fatal("expected series to be already deleted")
+	}
+	mt.Assert(testGauge.Name(), map[string]string{"kind": "live", "name": "a"}, monitortest.DoesNotExist)
+
+	if n := testGauge.(opentelemetry.Deleter).DeletePartialMatch(kind.Upsert("stale")); n != 2 {
+		t.Fatalf("want 2 deleted series, got %d", n)
+	}
+	mt.Assert(testGauge.Name(), map[string]string{"kind": "stale"}, monitortest.DoesNotExist)
+
+	// recording again brings back the series
+	testGauge.With(kind.Upsert("live"), name.Upsert("a")).Record(4)
+	mt.Assert(testGauge.Name(), map[string]string{"kind": "live", "name": "a"}, monitortest.Exactly(4))
+}
+
+func TestDerivedGaugeDelete(t *testing.T) {
+	mt := monitortest.New(t)
+
+	dg := ms.NewDerivedGauge(
+		"test_derived_gauge_delete",
+		"Testing derived gauge deletion",
+	)
+	dg.ValueFrom(func() float64 { return 1 }, kind.Upsert("stale"), name.Upsert("a"))
+	dg.ValueFrom(func() float64 { return 2 }, kind.Upsert("stale"), name.Upsert("b"))
+	dg.ValueFrom(func() float64 { return 3 }, kind.Upsert("live"), name.Upsert("a"))
+	mt.Assert(dg.Name(), map[string]string{"kind": "stale", "name": "b"}, monitortest.Exactly(2))
+
+	d := dg.(opentelemetry.Deleter)
+	if !d.Delete(kind.Upsert("stale"), name.Upsert("a")) {
+		t.Fatal("expected series to be deleted")
+	}
+	mt.Assert(dg.Name(), map[string]string{"kind": "stale", "name": "a"}, monitortest.DoesNotExist)
+	mt.Assert(dg.Name(), map[string]string{"kind": "stale", "name": "b"}, monitortest.Exactly(2))
+
+	if n := d.DeletePartialMatch(name.Upsert("b")); n != 1 {
+		t.Fatalf("want 1 deleted series, got %d", n)
+	}
+	mt.Assert(dg.Name(), map[string]string{"kind": "stale"}, monitortest.DoesNotExist)
+	mt.Assert(dg.Name(), map[string]string{"kind": "live", "name": "a"}, monitortest.Exactly(3))
+}
+
+func TestGaugeDeleteCardinalityLimit(t *testing.T) {
+	mt := monitortest.New(t)
+
+	g := ms.NewGauge("test_gauge_delete_limited", "Testing gauge deletion with a cardinality limit",
+		opentelemetry.WithCardinalityLimit(2))
+	a := g.With(name.Upsert("a"))
+	a.Record(1)
+	g.With(name.Upsert("b")).Record(2)
+
+	d := g.(opentelemetry.Deleter)
+	if !d.Delete(name.Upsert("a")) {
+		t.Fatal("expected series to be deleted")
+	}
+	if n := d.DeletePartialMatch(name.Upsert("b")); n != 1 {
+		t.Fatalf("want 1 deleted series, got %d", n)
+	}
+
+	// deleted series free their slot for new label sets
+	g.With(name.Upsert("c")).Record(3)
+	g.With(name.Upsert("d")).Record(4)
+	mt.Assert(g.Name(), map[string]string{"name": "c"}, monitortest.Exactly(3))
+	mt.Assert(g.Name(), map[string]string{"name": "d"}, monitortest.Exactly(4))
+	mt.Assert(g.Name(), map[string]string{"otel.metric.overflow": "true"}, monitortest.DoesNotExist)
+
+	// a deleted series recorded again is subject to the limit
+	a.Record(5)
+	mt.Assert(g.Name(), map[string]string{"name": "a"}, monitortest.DoesNotExist)
+	mt.Assert(g.Name(), map[string]string{"otel.metric.overflow": "true"}, monitortest.Exactly(5))
+
+	// a series created over the limit is admitted once a slot is released
+	e := g.With(name.Upsert("e"))
+	e.Record(6)
+	mt.Assert(g.Name(), map[string]string{"otel.metric.overflow": "true"}, monitortest.Exactly(6))
+	if !d.Delete(name.Upsert("c")) {
+		t.Fatal("expected series to be deleted")
+	}
+	e.Record(7)
+	mt.Assert(g.Name(), map[string]string{"name": "e"}, monitortest.Exactly(7))
+}
+
+func TestDeleteInvalidLabelValue(t *testing.T) {
+	mt := monitortest.New(t)
+
+	g := ms.NewGauge("test_gauge_delete_invalid", "Testing deletion with invalid label values")
+	g.Record(1)
+	g.With(name.Upsert("a")).Record(2)
+	dg := ms.NewDerivedGauge("test_derived_gauge_delete_invalid", "Testing deletion with invalid label values")
+	dg.ValueFrom(func() float64 { return 1 })
+	dg.ValueFrom(func() float64 { return 2 }, name.Upsert("a"))
+
+	for _, d := range []opentelemetry.Deleter{g.(opentelemetry.Deleter), dg.(opentelemetry.Deleter)} {
+		if d.Delete(name.Upsert("café")) {
+			t.Fatal("expected no series to be deleted")
+		}
+		if n := d.DeletePartialMatch(name.Upsert("café")); n != 0 {
+			t.Fatalf("want 0 deleted series, got %d", n)
+		}
+	}
+	mt.Assert(g.Name(), map[string]string{"name": "a"}, monitortest.Exactly(2))
+	mt.Assert(dg.Name(), map[string]string{"name": "a"}, monitortest.Exactly(2))
+
+	// the unlabelled series are still there
+	for _, d := range []opentelemetry.Deleter{g.(opentelemetry.Deleter), dg.(opentelemetry.Deleter)} {
+		if !d.Delete() {
+			t.Fatal("expected unlabelled series to be deleted")
+		}
+	}
+}
+
 func TestDerivedGauge(t *testing.T) {
 	mt := monitortest.New(t)
 	mt.Assert(testDerivedGauge.Name(), nil, monitortest.Exactly(17.76))
 }
 
 func TestDerivedGaugeWithLabels(t *testing.T) {
-	t.Skipf("skip for now")
 	foo := ms.NewLabel("foo")
 	testDerivedGaugeLabels.ValueFrom(
 		func() float64 {
@@ -176,20 +681,74 @@ func TestDerivedGaugeWithLabels(t *testing.T) {
 		foo.Upsert("baz"),
 	)
 
+	testDerivedGaugeLabels.ValueFrom(
+		func() float64 {
+			return 19.02
+		},
+		foo.Upsert("baz"),
+		kind.Upsert("goofy"),
+	)
+
+	testDerivedGaugeLabels.ValueFrom(
+		func() float64 {
+			return 20.5
+		},
+		foo.Upsert("qux"),
+		kind.Upsert("goofy"),
+		kind.Upsert("gopher"),
+	)
+
 	mt := monitortest.New(t)
 
 	cases := []struct {
-		wantLabel string
+		name      string
+		wantTags  map[string]string
 		wantValue float64
 	}{
-		{"bar", 17.76},
-		{"baz", 18.12},
+		{"bar", map[string]string{"foo": "bar"}, 17.76},
+		{"baz", map[string]string{"foo": "baz"}, 18.12},
+		{"baz goofy", map[string]string{"foo": "baz", "kind": "goofy"}, 19.02},
+		{"qux gopher", map[string]string{"foo": "qux", "kind": "gopher"}, 20.5},
 	}
 	for _, tc := range cases {
-		t.Run(tc.wantLabel, func(tt *testing.T) {
-			mt.Assert(testDerivedGaugeLabels.Name(), map[string]string{"foo": tc.wantLabel}, monitortest.Exactly(tc.wantValue))
+		t.Run(tc.name, func(tt *testing.T) {
+			mt.Assert(testDerivedGaugeLabels.Name(), tc.wantTags, monitortest.Exactly(tc.wantValue))
 		})
 	}
+	// series only carry the labels they were registered with.
+	mt.Assert(testDerivedGaugeLabels.Name(), map[string]string{"foo": "bar", "kind": "goofy"}, monitortest.DoesNotExist)
+}
+
+func TestDerivedGaugeStrictDimensions(t *testing.T) {
+	mt := monitortest.New(t)
+
+	strict := opentelemetry.New("test",
+		opentelemetry.WithMeterProvider(monitortest.MeterProvider()),
+		opentelemetry.WithStrictDimensions(),
+	)
+	dg := strict.NewDerivedGaugeWithOptions(
+		"test_derived_gauge_strict",
+		"Testing derived gauge strict dimensions",
+		telemetry.WithLabels(kind),
+		telemetry.WithUnit(telemetry.Bytes),
+	)
+	dg.ValueFrom(func() float64 { return 42 }, kind.Upsert("strict"), name.Upsert("dropped"))
+
+	mt.Assert(dg.Name(), map[string]string{"kind": "strict"}, monitortest.Exactly(42))
+	mt.Assert(dg.Name(), map[string]string{"name": "dropped"}, monitortest.DoesNotExist)
+}
+
+func TestDerivedGaugeDisabled(t *testing.T) {
+	mt := monitortest.New(t)
+
+	dg := ms.NewDerivedGaugeWithOptions(
+		"test_derived_gauge_disabled",
+		"Testing disabled derived gauge",
+		telemetry.WithEnabled(func() bool { return false }),
+	)
+	dg.ValueFrom(func() float64 { return 42 })
+
+	mt.Assert(dg.Name(), nil, monitortest.DoesNotExist)
 }
 
 func TestDistribution(t *testing.T) {
@@ -206,40 +765,454 @@ func TestDistribution(t *testing.T) {
 	mt.Assert(testDistribution.Name(), map[string]string{"name": "foo"}, monitortest.Buckets(7))
 }
 
-//func TestRecordHook(t *testing.T) {
-//	mt := monitortest.New(t)
-//
-//	// testRecordHook will record value for hookSum measure when testSum is recorded
-//	rh := &testRecordHook{}
-//	ms.RegisterRecordHook(testSum.Name(), rh)
-//
-//	testSum.With(name.Upsert("foo"), kind.Upsert("bart")).Increment()
-//	testSum.With(name.Upsert("baz"), kind.Upsert("bart")).Record(45)
-//
-//	mt.Assert(testSum.Name(), map[string]string{"name": "foo", "kind": "bart"}, monitortest.Exactly(1))
-//	mt.Assert(testSum.Name(), map[string]string{"name": "baz", "kind": "bart"}, monitortest.Exactly(45))
-//	mt.Assert(hookSum.Name(), map[string]string{"name": "foo"}, monitortest.Exactly(1))
-//	mt.Assert(hookSum.Name(), map[string]string{"name": "baz"}, monitortest.Exactly(45))
-//}
-//
-//type testRecordHook struct{}
-//
-//func (r *testRecordHook) OnRecord(n string, tags []monitoring.LabelValue, value float64) {
-//	// Check if this is `events_total` metric.
-//	if n != "events_total" {
-//		return
-//	}
-//
-//	// Get name tag of recorded testSum metric, and record the corresponding hookSum metric.
-//	var nv string
-//	for _, tag := range tags {
-//		if tag.Key() == name {
-//			nv = tag.Value()
-//			break
-//		}
-//	}
-//	hookSum.With(name.Upsert(nv)).Record(value)
-//}
+// lastRecordHook keeps the last recording of a metric.
+type lastRecordHook struct {
+	labels attribute.Set
+	value  float64
+}
+
+func (r *lastRecordHook) OnRecord(_ string, labels attribute.Set, value float64) {
+	r.labels, r.value = labels, value
+}
+
+func TestTimer(t *testing.T) {
+	seconds := ms.NewDistribution("timer_seconds", "Timer in seconds", []float64{1, 2},
+		telemetry.WithUnit(telemetry.Seconds))
+	millis := ms.NewDistribution("timer_milliseconds", "Timer in milliseconds", []float64{1000, 2000},
+		telemetry.WithUnit(telemetry.Milliseconds))
+	secondsHook, millisHook := &lastRecordHook{}, &lastRecordHook{}
+	ms.RegisterRecordHook(seconds.Name(), secondsHook)
+	ms.RegisterRecordHook(millis.Name(), millisHook)
+
+	start := time.Now().Add(-1500 * time.Millisecond)
+	if d := opentelemetry.Since(seconds, start); d < 1500*time.Millisecond {
+		t.Errorf("want at least 1.5s elapsed, got %v", d)
+	}
+	if secondsHook.value < 1.5 || secondsHook.value > 60 {
+		t.Errorf("want about 1.5 seconds recorded, got %v", secondsHook.value)
+	}
+
+	ctx, err := ms.ContextWithLabels(context.Background(), name.Insert("ctx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opentelemetry.SinceContext(ctx, millis.With(kind.Insert("with")), start)
+	if millisHook.value < 1500 || millisHook.value > 60000 {
+		t.Errorf("want about 1500 milliseconds recorded, got %v", millisHook.value)
+	}
+	for k, want := range map[attribute.Key]string{"name": "ctx", "kind": "with"} {
+		if got, _ := millisHook.labels.Value(k); got.AsString() != want {
+			t.Errorf("want label %s=%s, got %v", k, want, got.AsString())
+		}
+	}
+
+	timer := opentelemetry.NewTimer(millis)
+	if d := timer.Stop(); millisHook.value != float64(d)/float64(time.Millisecond) {
+		t.Errorf("want %v recorded, got %v milliseconds", d, millisHook.value)
+	}
+}
+
+func TestTimerUnits(t *testing.T) {
+	logger := &errorLogger{}
+	sink := opentelemetry.New("timer-test",
+		opentelemetry.WithMeterProvider(monitortest.MeterProvider()),
+		opentelemetry.WithLogger(logger),
+	)
+	hook := &lastRecordHook{}
+	sink.RegisterRecordHook("timer_microseconds", hook)
+	micros := sink.NewDistribution("timer_microseconds", "Timer in microseconds", []float64{1000},
+		telemetry.WithUnit(opentelemetry.Microseconds), opentelemetry.WithValueType(opentelemetry.ValueTypeInt64))
+	if d := opentelemetry.Since(micros, time.Now().Add(-time.Millisecond)); hook.value != float64(d)/float64(time.Microsecond) {
+		t.Errorf("want %v recorded, got %v microseconds", d, hook.value)
+	}
+	if len(logger.errs) != 0 {
+		t.Errorf("want no errors logged, got %v", logger.errs)
+	}
+
+	// sub-second durations recorded to integer seconds are truncated to 0.
+	sink.NewDistribution("timer_int_seconds", "Timer in integer seconds", []float64{1},
+		telemetry.WithUnit(telemetry.Seconds), opentelemetry.WithValueType(opentelemetry.ValueTypeInt64))
+	if len(logger.errs) != 1 {
+		t.Errorf("want the coarse unit logged, got %v", logger.errs)
+	}
+}
+
+func TestInt64(t *testing.T) {
+	reader := sdk.NewManualReader()
+	sink := opentelemetry.New("int64-test",
+		opentelemetry.WithMeterProvider(sdk.NewMeterProvider(sdk.WithReader(reader))))
+
+	sink.NewSum("received_bytes_total", "Received bytes", telemetry.WithUnit(telemetry.Bytes)).Record(1 << 60)
+	sink.NewSum("events_total", "Events").Record(1.5)
+	sink.NewSum("requests_total", "Requests", opentelemetry.WithValueType(opentelemetry.ValueTypeInt64)).Record(2.6)
+	sink.NewUpDownSum("in_flight", "In flight", opentelemetry.WithValueType(opentelemetry.ValueTypeInt64)).Record(-2)
+	sink.NewGauge("queue_size", "Queue size", opentelemetry.WithValueType(opentelemetry.ValueTypeInt64)).Record(7)
+	sink.NewDerivedGaugeWithOptions("memory_bytes", "Memory", telemetry.WithUnit(telemetry.Bytes)).
+		ValueFrom(func() float64 { return 1024 })
+	sink.NewDistribution("response_bytes", "Response size", []float64{10, 100},
+		telemetry.WithUnit(telemetry.Bytes)).Record(42)
+	sink.NewSum("cache_bytes_total", "Cache", telemetry.WithUnit(telemetry.Bytes),
+		opentelemetry.WithValueType(opentelemetry.ValueTypeFloat64)).Record(0.5)
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]metricdata.Aggregation{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			got[m.Name] = m.Data
+		}
+	}
+
+	assertInt := func(name string, want int64) {
+		t.Helper()
+		var dps []metricdata.DataPoint[int64]
+		switch data := got[name].(type) {
+		case metricdata.Sum[int64]:
+			dps = data.DataPoints
+		case metricdata.Gauge[int64]:
+			dps = data.DataPoints
+		default:
+			t.Errorf("want %s int64 data points, got %T", name, got[name])
+			return
+		}
+		if len(dps) != 1 || dps[0].Value != want {
+			t.Errorf("want %s %d, got %v", name, want, dps)
+		}
+	}
+	assertInt("received_bytes_total", 1<<60)
+	assertInt("requests_total", 3)
+	assertInt("in_flight", -2)
+	assertInt("queue_size", 7)
+	assertInt("memory_bytes", 1024)
+	if h, ok := got["response_bytes"].(metricdata.Histogram[int64]); !ok || h.DataPoints[0].Sum != 42 {
+		t.Errorf("want response_bytes int64 histogram, got %v", got["response_bytes"])
+	}
+	for _, name := range []string{"events_total", "cache_bytes_total"} {
+		if _, ok := got[name].(metricdata.Sum[float64]); !ok {
+			t.Errorf("want %s float64 data points, got %T", name, got[name])
+		}
+	}
+}
+
+func TestTypedLabels(t *testing.T) {
+	reader := sdk.NewManualReader()
+	sink := opentelemetry.New("typed-test",
+		opentelemetry.WithMeterProvider(sdk.NewMeterProvider(sdk.WithReader(reader))))
+
+	retries := sink.NewLabel("retries").(opentelemetry.TypedLabel)
+	cached := sink.NewLabel("cached").(opentelemetry.TypedLabel)
+	route := sink.NewLabel("route").(opentelemetry.TypedLabel)
+	requests := sink.NewSum("typed_requests_total", "Requests")
+
+	requests.With(retries.InsertInt64(3), cached.InsertBool(true), route.Insert("/")).Increment()
+	ctx, err := sink.ContextWithLabels(context.Background(),
+		retries.UpsertInt64(0), cached.UpsertBool(false), route.Upsert("/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests.RecordContext(ctx, 1)
+	// typed values do not collide with the string values they format to.
+	requests.With(retries.Insert("3"), cached.Insert("true"), route.Insert("/")).Increment()
+
+	var rm metricdata.ResourceMetrics
+	if err = reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	sum := rm.ScopeMetrics[0].Metrics[0].Data.(metricdata.Sum[float64])
+	want := []attribute.Set{
+		attribute.NewSet(attribute.Int64("retries", 3), attribute.Bool("cached", true), attribute.String("route", "/")),
+		attribute.NewSet(attribute.Int64("retries", 0), attribute.Bool("cached", false), attribute.String("route", "/")),
+		attribute.NewSet(attribute.String("retries", "3"), attribute.String("cached", "true"), attribute.String("route", "/")),
+	}
+	if len(sum.DataPoints) != len(want) {
+		t.Fatalf("want %d data points, got %v", len(want), sum.DataPoints)
+	}
+	for _, w := range want {
+		found := false
+		for _, dp := range sum.DataPoints {
+			if dp.Attributes.Equals(&w) && dp.Value == 1 {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("want data point with %v, got %v", w.Encoded(attribute.DefaultEncoder()), sum.DataPoints)
+		}
+	}
+}
+
+func TestRecordHook(t *testing.T) {
+	mt := monitortest.New(t)
+
+	// testRecordHook will record value for hookSum measure when testSum is recorded
+	rh := &testRecordHook{}
+	ms.RegisterRecordHook(testSum.Name(), rh)
+
+	testSum.With(name.Upsert("foo"), kind.Upsert("bart")).Increment()
+	testSum.With(name.Upsert("baz"), kind.Upsert("bart")).Record(45)
+
+	mt.Assert(testSum.Name(), map[string]string{"name": "foo", "kind": "bart"}, monitortest.Exactly(1))
+	mt.Assert(testSum.Name(), map[string]string{"name": "baz", "kind": "bart"}, monitortest.Exactly(45))
+	mt.Assert(hookSum.Name(), map[string]string{"name": "foo"}, monitortest.Exactly(1))
+	mt.Assert(hookSum.Name(), map[string]string{"name": "baz"}, monitortest.Exactly(45))
+}
+
+type testRecordHook struct{}
+
+func (r *testRecordHook) OnRecord(n string, labels attribute.Set, value float64) {
+	// Check if this is `events_total` metric.
+	if n != "events_total" {
+		return
+	}
+
+	// Get name label of recorded testSum metric, and record the corresponding hookSum metric.
+	nv, _ := labels.Value("name")
+	hookSum.With(name.Upsert(nv.AsString())).Record(value)
+}
+
+func TestExemplars(t *testing.T) {
+	monitortest.New(t)
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x01, 0x02, 0x03},
+		SpanID:     trace.SpanID{0x04, 0x05, 0x06},
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), sc)
+
+	testSum.With(name.Upsert("exemplar")).RecordContext(ctx, 1)
+	testDistribution.With(name.Upsert("exemplar")).RecordContext(ctx, 3)
+
+	families, err := monitortest.TestRegistry(t).Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found int
+	for _, mf := range families {
+		if mf.GetName() != testSum.Name() && mf.GetName() != testDistribution.Name() {
+			continue
+		}
+		for _, row := range mf.Metric {
+			if !hasLabel(row.Label, "name", "exemplar") {
+				continue
+			}
+			switch {
+			case row.Counter != nil:
+				assertExemplar(t, row.Counter.Exemplar, sc)
+				found++
+			case row.Histogram != nil:
+				for _, b := range row.Histogram.Bucket {
+					if b.Exemplar != nil {
+						assertExemplar(t, b.Exemplar, sc)
+						found++
+					}
+				}
+			}
+		}
+	}
+	if found != 2 {
+		t.Fatalf("want 2 exemplars, got %d", found)
+	}
+}
+
+func hasLabel(labels []*dto.LabelPair, name, value string) bool {
+	for _, l := range labels {
+		if l.GetName() == name && l.GetValue() == value {
+			return true
+		}
+	}
+	return false
+}
+
+func assertExemplar(t *testing.T, e *dto.Exemplar, sc trace.SpanContext) {
+	t.Helper()
+	if e == nil {
+		t.Fatal("missing exemplar")
+	}
+	if !hasLabel(e.Label, "trace_id", sc.TraceID().String()) {
+		t.Errorf("want exemplar trace_id %v, got %v", sc.TraceID(), e.Label)
+	}
+	if !hasLabel(e.Label, "span_id", sc.SpanID().String()) {
+		t.Errorf("want exemplar span_id %v, got %v", sc.SpanID(), e.Label)
+	}
+}
+
+func TestResource(t *testing.T) {
+	t.Setenv("OTEL_RESOURCE_ATTRIBUTES", "host.name=node-1,service.version=from-env")
+
+	cases := []struct {
+		name        string
+		serviceName string
+		want        map[attribute.Key]string
+	}{
+		{
+			name: "app name",
+			want: map[attribute.Key]string{
+				"service.name":    "resource-test",
+				"service.version": "from-env",
+				"k8s.pod.name":    "pod-1",
+				"host.name":       "node-1",
+			},
+		},
+		{
+			name:        "service name from env",
+			serviceName: "from-env",
+			want: map[attribute.Key]string{
+				"service.name": "from-env",
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("OTEL_SERVICE_NAME", tc.serviceName)
+			sink := opentelemetry.New("resource-test",
+				opentelemetry.WithResource(resource.NewSchemaless(
+					attribute.String("service.version", "1.2.3"),
+					attribute.String("k8s.pod.name", "pod-1"),
+				)),
+				opentelemetry.WithResourceFromEnv(),
+			)
+			reader := sdk.NewManualReader()
+			_ = sdk.NewMeterProvider(append(opentelemetry.Start(sink), sdk.WithReader(reader))...)
+			var rm metricdata.ResourceMetrics
+			if err := reader.Collect(context.Background(), &rm); err != nil {
+				t.Fatal(err)
+			}
+			for k, want := range tc.want {
+				if got, _ := rm.Resource.Set().Value(k); got.AsString() != want {
+					t.Errorf("want resource %v=%v, got %v", k, want, got.AsString())
+				}
+			}
+		})
+	}
+}
+
+// TestRegisteredExporter covers the views applied when the exporter is
+// installed globally. As the global MeterProvider can only be delegated once,
+// all cases share a single sink.
+func TestRegisteredExporter(t *testing.T) {
+	sink := opentelemetry.New("registered-test")
+	size := sink.NewDistribution("response_size", "Response size", nil,
+		opentelemetry.WithExponentialHistogram(20, 4))
+	latency := sink.NewDistribution("response_latency", "Response latency", []float64{1, 2})
+
+	reg := prometheus.NewRegistry()
+	exp, err := otelprom.RegisterPrometheusExporter(sink, reg, reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = exp.Shutdown(context.Background()) }()
+
+	t.Run("aggregation definitions", func(t *testing.T) {
+		defs := map[string]string{}
+		for _, def := range sink.(interface {
+			ExportMetricDefinitions() []opentelemetry.MetricDefinition
+		}).ExportMetricDefinitions() {
+			defs[def.Name] = def.Aggregation
+		}
+		if got := defs["response_size"]; got != opentelemetry.AggregationBase2ExponentialHistogram {
+			t.Errorf("want response_size aggregation %v, got %v", opentelemetry.AggregationBase2ExponentialHistogram, got)
+		}
+		if got := defs["response_latency"]; got != opentelemetry.AggregationExplicitBucketHistogram {
+			t.Errorf("want response_latency aggregation %v, got %v", opentelemetry.AggregationExplicitBucketHistogram, got)
+		}
+	})
+
+	t.Run("exponential", func(t *testing.T) {
+		size.Record(1024)
+		size.Record(3)
+		assertNativeHistogram(t, findHistogram(t, reg, "response_size"), 4, 2)
+	})
+
+	t.Run("explicit", func(t *testing.T) {
+		latency.Record(1.5)
+		assertBuckets(t, findHistogram(t, reg, "response_latency"), []float64{1, 2})
+	})
+
+	t.Run("late registration", func(t *testing.T) {
+		sink.NewDistribution("late_latency", "Late latency", []float64{7, 9}).Record(8)
+		sink.NewDistribution("late_size", "Late size", nil,
+			opentelemetry.WithExponentialHistogram(20, 2)).Record(8)
+		assertBuckets(t, findHistogram(t, reg, "late_latency"), []float64{7, 9})
+		assertNativeHistogram(t, findHistogram(t, reg, "late_size"), 2, 1)
+	})
+}
+
+func TestMeterProviderConflictingSinks(t *testing.T) {
+	newSink := func(mp *opentelemetry.MeterProvider, opts ...opentelemetry.SinkOption) *errorLogger {
+		logger := &errorLogger{}
+		opentelemetry.New("conflict-test", append(opts,
+			opentelemetry.WithMeterProvider(mp), opentelemetry.WithLogger(logger))...)
+		return logger
+	}
+	version := func(v string) opentelemetry.SinkOption {
+		return opentelemetry.WithResource(resource.NewSchemaless(attribute.String("service.version", v)))
+	}
+
+	mp := opentelemetry.NewMeterProvider()
+	newSink(mp, opentelemetry.WithExemplarFilter(opentelemetry.ExemplarFilterNever), version("1"))
+	if l := newSink(mp, opentelemetry.WithExemplarFilter(opentelemetry.ExemplarFilterNever), version("1")); len(l.errs) != 0 {
+		t.Errorf("want no errors for matching settings, got %v", l.errs)
+	}
+	if l := newSink(mp); len(l.errs) != 0 {
+		t.Errorf("want no errors for default settings, got %v", l.errs)
+	}
+	if l := newSink(mp, opentelemetry.WithExemplarFilter(opentelemetry.ExemplarFilterAlways), version("2")); len(l.errs) != 2 {
+		t.Errorf("want the exemplar filter and resource conflicts logged, got %v", l.errs)
+	}
+
+	// settings of sinks attached once the provider was built are not applied.
+	mp = opentelemetry.NewMeterProvider()
+	newSink(mp)
+	if l := newSink(mp, version("1")); len(l.errs) != 1 {
+		t.Errorf("want the resource conflict logged, got %v", l.errs)
+	}
+}
+
+func findHistogram(t *testing.T, gat prometheus.Gatherer, name string) *dto.Histogram {
+	t.Helper()
+	mfs, err := gat.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mf := range mfs {
+		if mf.GetName() == name {
+			return mf.Metric[0].GetHistogram()
+		}
+	}
+	t.Fatalf("%s not found", name)
+	return nil
+}
+
+func assertNativeHistogram(t *testing.T, h *dto.Histogram, maxSchema int32, count uint64) {
+	t.Helper()
+	if h.Schema == nil {
+		t.Fatal("want a native histogram")
+	}
+	if h.GetSchema() > maxSchema {
+		t.Errorf("want schema at most %d, got %d", maxSchema, h.GetSchema())
+	}
+	if h.GetSampleCount() != count {
+		t.Errorf("want %d samples, got %d", count, h.GetSampleCount())
+	}
+}
+
+func assertBuckets(t *testing.T, h *dto.Histogram, want []float64) {
+	t.Helper()
+	var got []float64
+	for _, b := range h.Bucket {
+		got = append(got, b.GetUpperBound())
+	}
+	if len(got) != len(want) {
+		t.Fatalf("want buckets %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("want buckets %v, got %v", want, got)
+		}
+	}
+}
 
 func BenchmarkCounter(b *testing.B) {
 	monitortest.New(b)
@@ -261,6 +1234,36 @@ func BenchmarkCounter(b *testing.B) {
 	})
 }
 
+func BenchmarkWith(b *testing.B) {
+	uncached := opentelemetry.New("test",
+		opentelemetry.WithMeterProvider(monitortest.MeterProvider()),
+		opentelemetry.WithLabelCacheSize(0),
+	)
+	uncachedName := uncached.NewLabel("name")
+	uncachedSum := uncached.NewSum("events_uncached_total", "Number of events observed, without label cache")
+
+	b.Run("cached", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			testSum.With(name.Upsert("test"), kind.Upsert("bench")).Increment()
+		}
+	})
+	b.Run("uncached", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			uncachedSum.With(uncachedName.Upsert("test")).Increment()
+		}
+	})
+	b.Run("cached parallel", func(b *testing.B) {
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				testSum.With(name.Upsert("test"), kind.Upsert("bench")).Increment()
+			}
+		})
+	})
+}
+
 func BenchmarkGauge(b *testing.B) {
 	monitortest.New(b)
 	b.Run("no labels", func(b *testing.B) {
@@ -280,3 +1283,46 @@ func BenchmarkGauge(b *testing.B) {
 		}
 	})
 }
+
+func BenchmarkGaugeParallel(b *testing.B) {
+	monitortest.New(b)
+	b.Run("shared series", func(b *testing.B) {
+		testGauge := testGauge.With(name.Upsert("parallel"))
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				testGauge.Record(1)
+			}
+		})
+	})
+	b.Run("series per goroutine", func(b *testing.B) {
+		var id atomic.Int64
+		b.RunParallel(func(pb *testing.PB) {
+			testGauge := testGauge.With(name.Upsert(strconv.FormatInt(id.Add(1), 10)))
+			for pb.Next() {
+				testGauge.Record(1)
+			}
+		})
+	})
+	b.Run("while collecting", func(b *testing.B) {
+		reg := monitortest.TestRegistry(b)
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					_, _ = reg.Gather()
+				}
+			}
+		}()
+		var id atomic.Int64
+		b.RunParallel(func(pb *testing.PB) {
+			testGauge := testGauge.With(name.Upsert(strconv.FormatInt(id.Add(1), 10)))
+			for pb.Next() {
+				testGauge.Record(1)
+			}
+		})
+	})
+}
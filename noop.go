@@ -0,0 +1,119 @@
+// Copyright (c) Tetrate, Inc 2023.
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opentelemetry
+
+import (
+	"context"
+
+	"github.com/tetratelabs/telemetry"
+
+	"github.com/tetratelabs/telemetry-opentelemetry/internal/tag"
+)
+
+// noopMetric is returned for metrics rejected by the name validation of the
+// sink. It discards all recordings.
+type noopMetric struct {
+	name string
+}
+
+// Decrement implements Metric
+func (dm *noopMetric) Decrement() {}
+
+// Increment implements Metric
+func (dm *noopMetric) Increment() {}
+
+// Name implements Metric
+func (dm *noopMetric) Name() string {
+	return dm.name
+}
+
+// Record implements telemetry.Metric
+func (dm *noopMetric) Record(float64) {}
+
+// RecordContext implements telemetry.Metric
+func (dm *noopMetric) RecordContext(context.Context, float64) {}
+
+// With implements telemetry.Metric
+func (dm *noopMetric) With(...telemetry.LabelValue) telemetry.Metric {
+	return dm
+}
+
+// ValueFrom implements telemetry.DerivedMetric
+func (dm *noopMetric) ValueFrom(func() float64, ...telemetry.LabelValue) telemetry.DerivedMetric {
+	return dm
+}
+
+// Delete implements Deleter
+func (dm *noopMetric) Delete(...telemetry.LabelValue) bool {
+	return false
+}
+
+// DeletePartialMatch implements Deleter
+func (dm *noopMetric) DeletePartialMatch(...telemetry.LabelValue) int {
+	return 0
+}
+
+var (
+	_ telemetry.Metric        = (*noopMetric)(nil)
+	_ telemetry.DerivedMetric = (*noopMetric)(nil)
+	_ Deleter                 = (*noopMetric)(nil)
+)
+
+// noopLabel is returned for labels rejected by the name validation of the sink.
+// Its values leave the label set untouched.
+type noopLabel struct{}
+
+// Insert implements telemetry.Label
+func (noopLabel) Insert(string) telemetry.LabelValue { return noopMutator{} }
+
+// Update implements telemetry.Label
+func (noopLabel) Update(string) telemetry.LabelValue { return noopMutator{} }
+
+// Upsert implements telemetry.Label
+func (noopLabel) Upsert(string) telemetry.LabelValue { return noopMutator{} }
+
+// Delete implements telemetry.Label
+func (noopLabel) Delete() telemetry.LabelValue { return noopMutator{} }
+
+// InsertInt64 implements TypedLabel
+func (noopLabel) InsertInt64(int64) telemetry.LabelValue { return noopMutator{} }
+
+// UpdateInt64 implements TypedLabel
+func (noopLabel) UpdateInt64(int64) telemetry.LabelValue { return noopMutator{} }
+
+// UpsertInt64 implements TypedLabel
+func (noopLabel) UpsertInt64(int64) telemetry.LabelValue { return noopMutator{} }
+
+// InsertBool implements TypedLabel
+func (noopLabel) InsertBool(bool) telemetry.LabelValue { return noopMutator{} }
+
+// UpdateBool implements TypedLabel
+func (noopLabel) UpdateBool(bool) telemetry.LabelValue { return noopMutator{} }
+
+// UpsertBool implements TypedLabel
+func (noopLabel) UpsertBool(bool) telemetry.LabelValue { return noopMutator{} }
+
+type noopMutator struct{}
+
+// Mutate implements tag.Mutator
+func (noopMutator) Mutate(t *tag.Map) (*tag.Map, error) {
+	return t, nil
+}
+
+var (
+	_ TypedLabel  = noopLabel{}
+	_ tag.Mutator = noopMutator{}
+)
@@ -15,17 +15,98 @@
 
 package opentelemetry
 
-import "github.com/tetratelabs/telemetry"
+import (
+	"sync"
+	"sync/atomic"
 
-func (m *metricSink) createOptions(
-	name string, opts ...telemetry.MetricOption,
-) (telemetry.MetricOptions, telemetry.Metric) {
-	o := telemetry.MetricOptions{Unit: telemetry.None}
-	for _, opt := range opts {
-		opt(&o)
+	"github.com/tetratelabs/telemetry"
+)
+
+// metricOptions holds the telemetry.MetricOptions together with the options
+// specific to this implementation.
+type metricOptions struct {
+	telemetry.MetricOptions
+	cardinalityLimit int
+	exponential      *exponentialHistogram
+	valueType        ValueType
+	// enabled is shared by the metric and all its derived metrics, and can
+	// be toggled at runtime through the sink.
+	enabled *atomic.Bool
+}
+
+// exponentialHistogram holds the configuration of a base-2 exponential
+// histogram.
+type exponentialHistogram struct {
+	maxSize  int32
+	maxScale int32
+}
+
+// extendedOptions maps the telemetry.MetricOptions currently being built by
+// createOptions to their enclosing metricOptions, which allows the
+// implementation specific telemetry.MetricOption functions to reach them.
+var extendedOptions sync.Map // map[*telemetry.MetricOptions]*metricOptions
+
+// extendOption returns a telemetry.MetricOption which applies fn to the
+// implementation specific options. The returned option is a no-op when used
+// with other telemetry.MetricSink implementations.
+func extendOption(fn func(o *metricOptions)) telemetry.MetricOption {
+	return func(o *telemetry.MetricOptions) {
+		if mo, ok := extendedOptions.Load(o); ok {
+			fn(mo.(*metricOptions))
+		}
+	}
+}
+
+// WithCardinalityLimit sets the maximum number of label sets the metric will
+// record. Recordings for label sets beyond the limit are routed into a single
+// overflow series. It overrides the limit set with the
+// WithDefaultCardinalityLimit SinkOption; a limit of 0 disables the per-metric
+// limit. The limit set with WithSinkCardinalityLimit applies regardless.
+func WithCardinalityLimit(limit int) telemetry.MetricOption {
+	return extendOption(func(o *metricOptions) {
+		o.cardinalityLimit = limit
+	})
+}
+
+// DefaultExponentialHistogramMaxSize and DefaultExponentialHistogramMaxScale
+// are used by WithExponentialHistogram when provided with 0 values.
+const (
+	DefaultExponentialHistogramMaxSize  = 160
+	DefaultExponentialHistogramMaxScale = 20
+)
+
+// WithExponentialHistogram exports a Distribution as a base-2 exponential
+// histogram, which maps to OTLP exponential histograms and Prometheus native
+// histograms. maxSize is the maximum number of buckets and maxScale the
+// maximum resolution; the histogram scales down to fit the recorded values
+// into maxSize buckets. The option is ignored by other metric types.
+func WithExponentialHistogram(maxSize, maxScale int32) telemetry.MetricOption {
+	if maxSize <= 0 {
+		maxSize = DefaultExponentialHistogramMaxSize
+	}
+	if maxScale == 0 {
+		maxScale = DefaultExponentialHistogramMaxScale
+	}
+	return extendOption(func(o *metricOptions) {
+		o.exponential = &exponentialHistogram{maxSize: maxSize, maxScale: maxScale}
+	})
+}
+
+func (m *metricSink) createOptions(opts ...telemetry.MetricOption) metricOptions {
+	o := &metricOptions{
+		MetricOptions:    telemetry.MetricOptions{Unit: telemetry.None},
+		cardinalityLimit: m.cardinalityLimit,
 	}
-	if o.EnabledCondition != nil && !o.EnabledCondition() {
-		return o, &disabledMetric{name: name}
+	extendedOptions.Store(&o.MetricOptions, o)
+	for _, opt := range opts {
+		opt(&o.MetricOptions)
 	}
-	return o, nil
+	extendedOptions.Delete(&o.MetricOptions)
+	return *o
+}
+
+// isEnabled evaluates the EnabledCondition, which only determines whether the
+// metric starts out enabled.
+func (o metricOptions) isEnabled() bool {
+	return o.EnabledCondition == nil || o.EnabledCondition()
 }
@@ -0,0 +1,156 @@
+// Copyright (c) Tetrate, Inc 2023.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package grpctags provides gRPC interceptors propagating the label values
+// installed with ContextWithLabels from clients to servers.
+// Clients encode the label values of the outgoing context into the
+// grpc-tags-bin metadata, which servers decode into the incoming context.
+// Servers only accept the labels allowed with WithAcceptedLabels. Metrics
+// recorded with RecordContext on the server then pick the label values up
+// automatically.
+package grpctags
+
+import (
+	"context"
+
+	"github.com/tetratelabs/telemetry/scope"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/tetratelabs/telemetry-opentelemetry/internal/tag"
+)
+
+// MetadataKey is the gRPC metadata key carrying the binary encoded tags.
+const MetadataKey = "grpc-tags-bin"
+
+var log = scope.Register("telemetry-otel-grpc", "Messages from the telemetry-OTel gRPC interceptors")
+
+// Option configures the server interceptors.
+type Option func(*config)
+
+type config struct {
+	accepted map[string]bool
+}
+
+// WithAcceptedLabels accepts the values of the named labels propagated by the
+// client. Other tags are ignored, so clients can't create series for labels
+// the server does not expect.
+func WithAcceptedLabels(names ...string) Option {
+	return func(c *config) {
+		for _, name := range names {
+			c.accepted[name] = true
+		}
+	}
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{accepted: map[string]bool{}}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// accept reports whether the tag with key k is accepted.
+func (c *config) accept(k tag.Key) bool {
+	return c.accepted[k.Name()]
+}
+
+// UnaryClientInterceptor returns a client interceptor propagating the label
+// values found in the context of unary RPCs.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context, method string, req, reply any,
+		cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption,
+	) error {
+		return invoker(outgoing(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor returns a client interceptor propagating the label
+// values found in the context of streaming RPCs.
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(
+		ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn,
+		method string, streamer grpc.Streamer, opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		return streamer(outgoing(ctx), desc, cc, method, opts...)
+	}
+}
+
+// UnaryServerInterceptor returns a server interceptor installing the label
+// values received with unary RPCs in the context passed to the handler.
+func UnaryServerInterceptor(opts ...Option) grpc.UnaryServerInterceptor {
+	c := newConfig(opts)
+	return func(
+		ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler,
+	) (any, error) {
+		return handler(c.incoming(ctx), req)
+	}
+}
+
+// StreamServerInterceptor returns a server interceptor installing the label
+// values received with streaming RPCs in the context of the server stream.
+func StreamServerInterceptor(opts ...Option) grpc.StreamServerInterceptor {
+	c := newConfig(opts)
+	return func(
+		srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler,
+	) error {
+		return handler(srv, &serverStream{ServerStream: ss, ctx: c.incoming(ss.Context())})
+	}
+}
+
+// serverStream overrides the context of the wrapped grpc.ServerStream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// outgoing returns ctx with the tags it holds encoded in the outgoing metadata.
+func outgoing(ctx context.Context) context.Context {
+	m := tag.FromContext(ctx)
+	if m == nil || m.Len() == 0 {
+		return ctx
+	}
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+	md.Set(MetadataKey, string(tag.Encode(m)))
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// incoming returns ctx with the accepted tags decoded from the incoming
+// metadata. Malformed tags are ignored rather than failing the RPC.
+func (c *config) incoming(ctx context.Context) context.Context {
+	values := metadata.ValueFromIncomingContext(ctx, MetadataKey)
+	if len(values) == 0 || len(c.accepted) == 0 {
+		return ctx
+	}
+	m, err := tag.Decode([]byte(values[len(values)-1]))
+	if err != nil {
+		log.Debug("ignoring malformed tags", "error", err)
+		return ctx
+	}
+	if m = m.Filter(c.accept); m.Len() == 0 {
+		return ctx
+	}
+	return tag.Merge(ctx, m)
+}
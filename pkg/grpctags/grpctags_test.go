@@ -0,0 +1,154 @@
+// Copyright (c) Tetrate, Inc 2023.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpctags_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/tetratelabs/telemetry"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+
+	opentelemetry "github.com/tetratelabs/telemetry-opentelemetry"
+	"github.com/tetratelabs/telemetry-opentelemetry/internal/monitortest"
+	"github.com/tetratelabs/telemetry-opentelemetry/pkg/grpctags"
+)
+
+// healthServer records an RPC in the context each call is handled with,
+// defaulting the service label to "none" when it was not propagated.
+type healthServer struct {
+	healthpb.UnimplementedHealthServer
+	ms      telemetry.MetricSink
+	service telemetry.Label
+	rpcs    telemetry.Metric
+}
+
+func (s *healthServer) record(ctx context.Context) {
+	ctx, _ = s.ms.ContextWithLabels(ctx, s.service.Insert("none"))
+	s.rpcs.RecordContext(ctx, 1)
+}
+
+func (s *healthServer) Check(
+	ctx context.Context, _ *healthpb.HealthCheckRequest,
+) (*healthpb.HealthCheckResponse, error) {
+	s.record(ctx)
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+}
+
+func (s *healthServer) Watch(
+	_ *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer,
+) error {
+	s.record(stream.Context())
+	return stream.Send(&healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING})
+}
+
+func TestInterceptors(t *testing.T) {
+	ms := opentelemetry.New("test", opentelemetry.WithMeterProvider(monitortest.MeterProvider()))
+	service := ms.NewLabel("service")
+	caller := ms.NewLabel("caller")
+	rpcs := ms.NewSum("grpctags_rpcs_total", "Number of RPCs handled", telemetry.WithLabels(service, caller))
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(grpctags.UnaryServerInterceptor(grpctags.WithAcceptedLabels("service", "caller"))),
+		grpc.ChainStreamInterceptor(grpctags.StreamServerInterceptor(grpctags.WithAcceptedLabels("service"))),
+	)
+	healthpb.RegisterHealthServer(srv, &healthServer{ms: ms, service: service, rpcs: rpcs})
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(grpctags.UnaryClientInterceptor()),
+		grpc.WithChainStreamInterceptor(grpctags.StreamClientInterceptor()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	client := healthpb.NewHealthClient(conn)
+
+	t.Run("unary", func(t *testing.T) {
+		mt := monitortest.New(t)
+		ctx, err := ms.ContextWithLabels(context.Background(),
+			service.Insert("unary"), caller.Insert("client"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = client.Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+			t.Fatal(err)
+		}
+		mt.Assert("grpctags_rpcs_total", map[string]string{"service": "unary", "caller": "client"}, monitortest.Exactly(1))
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		mt := monitortest.New(t)
+		ctx, err := ms.ContextWithLabels(context.Background(), service.Insert("stream"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = stream.Recv(); err != nil {
+			t.Fatal(err)
+		}
+		mt.Assert("grpctags_rpcs_total", map[string]string{"service": "stream"}, monitortest.Exactly(1))
+	})
+
+	t.Run("unexpected label", func(t *testing.T) {
+		mt := monitortest.New(t)
+		tenant := ms.NewLabel("tenant")
+		ctx, err := ms.ContextWithLabels(context.Background(),
+			service.Insert("filtered"), tenant.Insert("t1"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = client.Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+			t.Fatal(err)
+		}
+		// only the labels accepted by the server are installed.
+		mt.Assert("grpctags_rpcs_total", map[string]string{"service": "filtered"}, monitortest.Exactly(1))
+		mt.Assert("grpctags_rpcs_total", map[string]string{"tenant": "t1"}, monitortest.DoesNotExist)
+	})
+
+	t.Run("without labels", func(t *testing.T) {
+		mt := monitortest.New(t)
+		if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+			t.Fatal(err)
+		}
+		mt.Assert("grpctags_rpcs_total", map[string]string{"service": "none"}, monitortest.Exactly(1))
+	})
+
+	t.Run("malformed metadata", func(t *testing.T) {
+		mt := monitortest.New(t)
+		ctx := metadata.AppendToOutgoingContext(context.Background(), grpctags.MetadataKey, "\x7fgarbage")
+		if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+			t.Fatal(err)
+		}
+		mt.Assert("grpctags_rpcs_total", map[string]string{"service": "none"}, monitortest.Exactly(1))
+	})
+}
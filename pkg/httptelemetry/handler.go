@@ -0,0 +1,218 @@
+// Copyright (c) Tetrate, Inc 2023.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httptelemetry
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/tetratelabs/telemetry"
+
+	opentelemetry "github.com/tetratelabs/telemetry-opentelemetry"
+	"github.com/tetratelabs/telemetry-opentelemetry/internal/tag"
+)
+
+type handler struct {
+	next    http.Handler
+	m       *metrics
+	baggage map[string]bool
+}
+
+// Option configures the server middleware.
+type Option func(*handler)
+
+// WithBaggageLabels accepts the values of the named labels propagated by the
+// client through the W3C baggage header. Other baggage members are ignored, so
+// clients can't create series for labels the server does not expect.
+func WithBaggageLabels(names ...string) Option {
+	return func(h *handler) {
+		for _, name := range names {
+			h.baggage[name] = true
+		}
+	}
+}
+
+// NewHandler returns middleware serving requests with next. The label values
+// propagated by the client for the labels accepted with WithBaggageLabels, as
+// well as the http.request.method, url.scheme and http.route labels are
+// installed in the request context. The route is known upfront when next is a
+// http.ServeMux or when the middleware is registered with one; otherwise it is
+// only recorded with the HTTP metrics, once a nested http.ServeMux routed the
+// request. Once served, the request duration and body sizes are recorded to ms
+// with the http.response.status_code label.
+func NewHandler(ms telemetry.MetricSink, next http.Handler, opts ...Option) http.Handler {
+	h := &handler{
+		next: next,
+		m: newMetrics(ms,
+			ServerRequestDuration, ServerRequestBodySize, ServerResponseBodySize, "server"),
+		baggage: map[string]bool{},
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	ctx := r.Context()
+	if header := r.Header.Get(tag.BaggageHeader); header != "" && len(h.baggage) > 0 {
+		if m, err := tag.DecodeBaggage(header); err != nil {
+			log.Debug("ignoring malformed baggage", "error", err)
+		} else if m = m.Filter(h.accept); m.Len() > 0 {
+			ctx = tag.Merge(ctx, m)
+		}
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	lvs := []telemetry.LabelValue{
+		h.m.method.Insert(methodValue(r.Method)),
+		h.m.scheme.Insert(scheme),
+	}
+	pattern := r.Pattern
+	if mux, ok := h.next.(*http.ServeMux); ok && pattern == "" {
+		_, pattern = mux.Handler(r)
+	}
+	if pattern != "" {
+		lvs = append(lvs, h.m.route.Insert(routeValue(pattern)))
+	}
+	ctx, err := h.m.ms.ContextWithLabels(ctx, lvs...)
+	if err != nil {
+		log.Error("unable to install HTTP labels", err)
+	}
+
+	var body *countingReader
+	if r.Body != nil && r.Body != http.NoBody {
+		body = &countingReader{ReadCloser: r.Body}
+	}
+	r = r.WithContext(ctx)
+	if body != nil {
+		r.Body = body
+	}
+	rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+	h.next.ServeHTTP(rw.wrap(), r)
+
+	// a http.ServeMux nested in next sets the pattern of the request it routed.
+	lvs = []telemetry.LabelValue{h.m.status.Insert(statusValue(rw.status))}
+	if pattern == "" && r.Pattern != "" {
+		lvs = append(lvs, h.m.route.Insert(routeValue(r.Pattern)))
+	}
+	if rw.status >= http.StatusInternalServerError {
+		lvs = append(lvs, h.m.errorType.Insert(statusValue(rw.status)))
+	}
+	if ctx, err = h.m.ms.ContextWithLabels(ctx, lvs...); err != nil {
+		log.Error("unable to install HTTP labels", err)
+	}
+
+	opentelemetry.SinceContext(ctx, h.m.duration, start)
+	requestSize := r.ContentLength
+	if requestSize < 0 && body != nil {
+		requestSize = body.n
+	}
+	if requestSize >= 0 {
+		h.m.requestSize.RecordContext(ctx, float64(requestSize))
+	}
+	h.m.responseSize.RecordContext(ctx, float64(rw.written))
+}
+
+// accept reports whether the baggage member with key k is accepted.
+func (h *handler) accept(k tag.Key) bool {
+	return h.baggage[k.Name()]
+}
+
+// responseWriter captures the status code and the number of bytes written.
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	written     int64
+	wroteHeader bool
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	// informational responses precede the final status, except for switching
+	// protocols.
+	if !w.wroteHeader && (code >= 200 || code == http.StatusSwitchingProtocols) {
+		w.status = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(b)
+	w.written += int64(n)
+	return n, err
+}
+
+// Unwrap allows http.ResponseController to reach the wrapped
+// http.ResponseWriter.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// wrap returns w implementing the optional http.Flusher and http.Hijacker
+// interfaces only when the wrapped http.ResponseWriter does, so handlers
+// checking for them with a type assertion keep their fallbacks.
+func (w *responseWriter) wrap() http.ResponseWriter {
+	f, isFlusher := w.ResponseWriter.(http.Flusher)
+	h, isHijacker := w.ResponseWriter.(http.Hijacker)
+	switch {
+	case isFlusher && isHijacker:
+		return struct {
+			*responseWriter
+			http.Flusher
+			http.Hijacker
+		}{w, flusher{w, f}, h}
+	case isFlusher:
+		return struct {
+			*responseWriter
+			http.Flusher
+		}{w, flusher{w, f}}
+	case isHijacker:
+		return struct {
+			*responseWriter
+			http.Hijacker
+		}{w, h}
+	default:
+		return w
+	}
+}
+
+// flusher flushes the wrapped http.ResponseWriter, which sends the header.
+type flusher struct {
+	w *responseWriter
+	f http.Flusher
+}
+
+func (f flusher) Flush() {
+	f.w.wroteHeader = true
+	f.f.Flush()
+}
+
+// countingReader counts the number of bytes read from a body.
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *countingReader) Read(b []byte) (int, error) {
+	n, err := r.ReadCloser.Read(b)
+	r.n += int64(n)
+	return n, err
+}
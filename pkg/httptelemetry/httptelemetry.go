@@ -0,0 +1,148 @@
+// Copyright (c) Tetrate, Inc 2023.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package httptelemetry provides net/http server middleware and a client
+// RoundTripper propagating label values and recording the HTTP metrics defined
+// by the OpenTelemetry semantic conventions
+// (https://opentelemetry.io/docs/specs/semconv/http/http-metrics/).
+//
+// Label values installed with ContextWithLabels are propagated from clients to
+// servers through the W3C baggage header. On the server, the propagated values
+// of the labels accepted with WithBaggageLabels as well as the request method,
+// scheme and route are installed in the request context, so metrics recorded
+// with RecordContext by handlers pick them up automatically.
+//
+// The number of requests is exposed as the count of the request duration
+// distributions.
+//
+// The metric and label names defined by the semantic conventions hold dots,
+// which sinks created with opentelemetry.NameValidationStrict reject: such
+// sinks record no HTTP metrics. Use opentelemetry.NameValidationSanitize to
+// record them with underscores instead, e.g. http_server_request_duration.
+package httptelemetry
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/tetratelabs/telemetry"
+	"github.com/tetratelabs/telemetry/scope"
+)
+
+var log = scope.Register("telemetry-otel-http", "Messages from the telemetry-OTel HTTP instrumentation")
+
+// Metric names defined by the OpenTelemetry semantic conventions.
+const (
+	ServerRequestDuration  = "http.server.request.duration"
+	ServerRequestBodySize  = "http.server.request.body.size"
+	ServerResponseBodySize = "http.server.response.body.size"
+	ClientRequestDuration  = "http.client.request.duration"
+	ClientRequestBodySize  = "http.client.request.body.size"
+	ClientResponseBodySize = "http.client.response.body.size"
+)
+
+// Label names defined by the OpenTelemetry semantic conventions.
+const (
+	LabelRequestMethod      = "http.request.method"
+	LabelResponseStatusCode = "http.response.status_code"
+	LabelRoute              = "http.route"
+	LabelScheme             = "url.scheme"
+	LabelServerAddress      = "server.address"
+	LabelServerPort         = "server.port"
+	LabelErrorType          = "error.type"
+)
+
+var (
+	// durationBounds are the bucket boundaries advised by the semantic
+	// conventions for request durations, in seconds.
+	durationBounds = []float64{0.005, 0.01, 0.025, 0.05, 0.075, 0.1, 0.25, 0.5, 0.75, 1, 2.5, 5, 7.5, 10}
+	// sizeBounds are the bucket boundaries used for body sizes, in bytes.
+	sizeBounds = []float64{0, 256, 1024, 4096, 16384, 65536, 262144, 1048576, 4194304, 16777216}
+)
+
+// metrics holds the labels and metrics recorded by either the server
+// middleware or the client transport.
+type metrics struct {
+	ms telemetry.MetricSink
+
+	method    telemetry.Label
+	status    telemetry.Label
+	route     telemetry.Label
+	scheme    telemetry.Label
+	address   telemetry.Label
+	port      telemetry.Label
+	errorType telemetry.Label
+
+	duration     telemetry.Metric
+	requestSize  telemetry.Metric
+	responseSize telemetry.Metric
+}
+
+func newMetrics(ms telemetry.MetricSink, duration, requestSize, responseSize, side string) *metrics {
+	m := &metrics{
+		ms:        ms,
+		method:    ms.NewLabel(LabelRequestMethod),
+		status:    ms.NewLabel(LabelResponseStatusCode),
+		route:     ms.NewLabel(LabelRoute),
+		scheme:    ms.NewLabel(LabelScheme),
+		address:   ms.NewLabel(LabelServerAddress),
+		port:      ms.NewLabel(LabelServerPort),
+		errorType: ms.NewLabel(LabelErrorType),
+	}
+	// the labels are declared for sinks with strict dimensions.
+	labels := telemetry.WithLabels(m.method, m.status, m.route, m.scheme, m.address, m.port, m.errorType)
+	m.duration = ms.NewDistribution(duration,
+		"Duration of HTTP "+side+" requests.",
+		durationBounds,
+		telemetry.WithUnit(telemetry.Seconds), labels)
+	m.requestSize = ms.NewDistribution(requestSize,
+		"Size of HTTP "+side+" request bodies.",
+		sizeBounds,
+		telemetry.WithUnit(telemetry.Bytes), labels)
+	m.responseSize = ms.NewDistribution(responseSize,
+		"Size of HTTP "+side+" response bodies.",
+		sizeBounds,
+		telemetry.WithUnit(telemetry.Bytes), labels)
+	return m
+}
+
+// methodValue returns the method as recorded by the http.request.method label.
+// Methods not defined by RFC 9110 or RFC 5789 are recorded as _OTHER to bound
+// cardinality.
+func methodValue(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions,
+		http.MethodTrace, http.MethodPatch:
+		return method
+	case "":
+		return http.MethodGet
+	default:
+		return "_OTHER"
+	}
+}
+
+// routeValue returns the path of a http.ServeMux pattern, as recorded by the
+// http.route label.
+func routeValue(pattern string) string {
+	if i := strings.IndexByte(pattern, '/'); i >= 0 {
+		return pattern[i:]
+	}
+	return pattern
+}
+
+func statusValue(code int) string {
+	return strconv.Itoa(code)
+}
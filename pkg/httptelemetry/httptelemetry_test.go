@@ -0,0 +1,282 @@
+// Copyright (c) Tetrate, Inc 2023.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httptelemetry_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdk "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	opentelemetry "github.com/tetratelabs/telemetry-opentelemetry"
+	"github.com/tetratelabs/telemetry-opentelemetry/internal/monitortest"
+	"github.com/tetratelabs/telemetry-opentelemetry/pkg/httptelemetry"
+)
+
+func TestHandlerAndTransport(t *testing.T) {
+	ms := opentelemetry.New("test", opentelemetry.WithMeterProvider(monitortest.MeterProvider()))
+	caller := ms.NewLabel("caller")
+	handled := ms.NewSum("httptelemetry_handled_total", "Number of requests handled")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		handled.RecordContext(r.Context(), 1)
+		_, _ = io.WriteString(w, "item "+r.PathValue("id"))
+	})
+	mux.HandleFunc("POST /items", func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		handled.RecordContext(r.Context(), 1)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write(b)
+	})
+	mux.HandleFunc("GET /fail", func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "fail", http.StatusInternalServerError)
+	})
+	srv := httptest.NewServer(httptelemetry.NewHandler(ms, mux, httptelemetry.WithBaggageLabels("caller")))
+	t.Cleanup(srv.Close)
+
+	client := &http.Client{Transport: httptelemetry.NewTransport(ms, nil)}
+	do := func(t *testing.T, method, path, body string, header ...string) {
+		t.Helper()
+		ctx, err := ms.ContextWithLabels(context.Background(), caller.Insert("client"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var r io.Reader
+		if body != "" {
+			r = strings.NewReader(body)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, srv.URL+path, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}
+
+	t.Run("get", func(t *testing.T) {
+		mt := monitortest.New(t)
+		do(t, http.MethodGet, "/items/42", "")
+
+		// handlers see the propagated and route labels.
+		mt.Assert("httptelemetry_handled_total", map[string]string{
+			"caller":              "client",
+			"http.request.method": "GET",
+			"http.route":          "/items/{id}",
+			"url.scheme":          "http",
+		}, monitortest.Exactly(1))
+		server := map[string]string{
+			"caller":                    "client",
+			"http.request.method":       "GET",
+			"http.route":                "/items/{id}",
+			"http.response.status_code": "200",
+		}
+		mt.Assert("http.server.request.duration", server, monitortest.Buckets(14))
+		mt.Assert("http.server.response.body.size", server, monitortest.Distribution(1, 7))
+		mt.Assert("http.client.request.duration", map[string]string{
+			"caller":                    "client",
+			"http.request.method":       "GET",
+			"http.response.status_code": "200",
+			"server.address":            "127.0.0.1",
+			"url.scheme":                "http",
+		}, monitortest.Buckets(14))
+		mt.Assert("http.client.response.body.size", map[string]string{
+			"caller":              "client",
+			"http.request.method": "GET",
+		}, monitortest.Distribution(1, 7))
+	})
+
+	t.Run("unexpected baggage", func(t *testing.T) {
+		mt := monitortest.New(t)
+		do(t, http.MethodGet, "/items/7", "", "baggage", "tenant=t1")
+
+		// only the labels accepted by the server are installed.
+		mt.Assert("httptelemetry_handled_total", map[string]string{"caller": "client"}, monitortest.Exactly(1))
+		mt.Assert("httptelemetry_handled_total", map[string]string{"tenant": "t1"}, monitortest.DoesNotExist)
+	})
+
+	t.Run("post", func(t *testing.T) {
+		mt := monitortest.New(t)
+		do(t, http.MethodPost, "/items", "hello")
+
+		server := map[string]string{
+			"http.request.method":       "POST",
+			"http.route":                "/items",
+			"http.response.status_code": "201",
+		}
+		mt.Assert("http.server.request.body.size", server, monitortest.Distribution(1, 5))
+		mt.Assert("http.server.response.body.size", server, monitortest.Distribution(1, 5))
+		mt.Assert("http.client.request.body.size", map[string]string{
+			"http.request.method":       "POST",
+			"http.response.status_code": "201",
+		}, monitortest.Distribution(1, 5))
+	})
+
+	t.Run("error", func(t *testing.T) {
+		mt := monitortest.New(t)
+		do(t, http.MethodGet, "/fail", "")
+
+		mt.Assert("http.server.request.duration", map[string]string{
+			"http.route":                "/fail",
+			"http.response.status_code": "500",
+			"error.type":                "500",
+		}, monitortest.Buckets(14))
+		mt.Assert("http.client.request.duration", map[string]string{
+			"http.response.status_code": "500",
+			"error.type":                "500",
+		}, monitortest.Buckets(14))
+	})
+}
+
+func TestTransportDoesNotModifyRequest(t *testing.T) {
+	ms := opentelemetry.New("test", opentelemetry.WithMeterProvider(monitortest.MeterProvider()))
+	caller := ms.NewLabel("caller")
+
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("baggage")
+	}))
+	t.Cleanup(srv.Close)
+
+	ctx, _ := ms.ContextWithLabels(context.Background(), caller.Insert("client"))
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	req.Header.Set("baggage", "other=value")
+	resp, err := httptelemetry.NewTransport(ms, nil).RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+
+	if want := "other=value,caller=client"; got != want {
+		t.Errorf("baggage = %q; want %q", got, want)
+	}
+	if got := req.Header.Get("baggage"); got != "other=value" {
+		t.Errorf("request baggage = %q; want it unmodified", got)
+	}
+}
+
+// plainResponseWriter implements none of the optional interfaces.
+type plainResponseWriter struct {
+	http.ResponseWriter
+}
+
+func TestResponseWriterInterfaces(t *testing.T) {
+	ms := opentelemetry.New("test", opentelemetry.WithMeterProvider(monitortest.MeterProvider()))
+	var flusher, hijacker bool
+	h := httptelemetry.NewHandler(ms, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, flusher = w.(http.Flusher)
+		_, hijacker = w.(http.Hijacker)
+	}))
+
+	tests := []struct {
+		name              string
+		w                 http.ResponseWriter
+		flusher, hijacker bool
+	}{
+		{"flusher", httptest.NewRecorder(), true, false},
+		{"plain", plainResponseWriter{httptest.NewRecorder()}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h.ServeHTTP(tt.w, httptest.NewRequest(http.MethodGet, "/", nil))
+			if flusher != tt.flusher || hijacker != tt.hijacker {
+				t.Errorf("want flusher=%v hijacker=%v, got %v %v", tt.flusher, tt.hijacker, flusher, hijacker)
+			}
+		})
+	}
+
+	t.Run("server", func(t *testing.T) {
+		srv := httptest.NewServer(h)
+		t.Cleanup(srv.Close)
+		resp, err := http.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		if !flusher || !hijacker {
+			t.Errorf("want flusher and hijacker, got %v %v", flusher, hijacker)
+		}
+	})
+}
+
+func TestSinkOptions(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []opentelemetry.SinkOption
+		metric string
+		label  attribute.Key
+	}{
+		{"strict dimensions", []opentelemetry.SinkOption{opentelemetry.WithStrictDimensions()},
+			httptelemetry.ServerRequestDuration, httptelemetry.LabelRequestMethod},
+		{"strict names", []opentelemetry.SinkOption{opentelemetry.WithNameValidation(opentelemetry.NameValidationStrict)},
+			"", ""},
+		{"sanitized names", []opentelemetry.SinkOption{opentelemetry.WithNameValidation(opentelemetry.NameValidationSanitize)},
+			"http_server_request_duration", "http_request_method"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := sdk.NewManualReader()
+			ms := opentelemetry.New("test", append(tt.opts,
+				opentelemetry.WithMeterProvider(sdk.NewMeterProvider(sdk.WithReader(reader))))...)
+			srv := httptest.NewServer(httptelemetry.NewHandler(ms, http.NotFoundHandler()))
+			t.Cleanup(srv.Close)
+			resp, err := http.Get(srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = resp.Body.Close()
+
+			var rm metricdata.ResourceMetrics
+			if err = reader.Collect(context.Background(), &rm); err != nil {
+				t.Fatal(err)
+			}
+			names := map[string]bool{}
+			for _, sm := range rm.ScopeMetrics {
+				for _, m := range sm.Metrics {
+					names[m.Name] = true
+					var attrs attribute.Set
+					switch data := m.Data.(type) {
+					case metricdata.Histogram[float64]:
+						attrs = data.DataPoints[0].Attributes
+					case metricdata.Histogram[int64]:
+						attrs = data.DataPoints[0].Attributes
+					}
+					if v, ok := attrs.Value(tt.label); !ok || v.AsString() != http.MethodGet {
+						t.Errorf("want %s %s=GET, got %v", m.Name, tt.label, attrs.ToSlice())
+					}
+				}
+			}
+			if tt.metric == "" && len(names) > 0 {
+				t.Errorf("want nothing recorded, got %v", names)
+			}
+			if tt.metric != "" && !names[tt.metric] {
+				t.Errorf("want %s recorded, got %v", tt.metric, names)
+			}
+		})
+	}
+}
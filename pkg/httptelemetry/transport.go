@@ -0,0 +1,140 @@
+// Copyright (c) Tetrate, Inc 2023.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httptelemetry
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/tetratelabs/telemetry"
+
+	opentelemetry "github.com/tetratelabs/telemetry-opentelemetry"
+	"github.com/tetratelabs/telemetry-opentelemetry/internal/tag"
+)
+
+type transport struct {
+	base http.RoundTripper
+	m    *metrics
+}
+
+// NewTransport returns a http.RoundTripper sending requests with base, or
+// http.DefaultTransport if nil. The label values found in the request context
+// are propagated to the server. The request duration and body sizes are
+// recorded to ms with the http.request.method, http.response.status_code,
+// server.address, server.port and url.scheme labels, on top of the label
+// values found in the request context.
+func NewTransport(ms telemetry.MetricSink, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{
+		base: base,
+		m: newMetrics(ms,
+			ClientRequestDuration, ClientRequestBodySize, ClientResponseBodySize, "client"),
+	}
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	ctx := req.Context()
+	if baggage := tag.EncodeBaggage(tag.FromContext(ctx)); baggage != "" {
+		// a RoundTripper must not modify the request it was provided.
+		req = req.Clone(ctx)
+		if header := req.Header.Get(tag.BaggageHeader); header != "" {
+			baggage = header + "," + baggage
+		}
+		req.Header.Set(tag.BaggageHeader, baggage)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+
+	lvs := []telemetry.LabelValue{
+		t.m.method.Insert(methodValue(req.Method)),
+		t.m.scheme.Insert(req.URL.Scheme),
+		t.m.address.Insert(req.URL.Hostname()),
+		t.m.port.Insert(portValue(req)),
+	}
+	switch {
+	case err != nil:
+		lvs = append(lvs, t.m.errorType.Insert(fmt.Sprintf("%T", err)))
+	case resp.StatusCode >= http.StatusBadRequest:
+		lvs = append(lvs,
+			t.m.status.Insert(statusValue(resp.StatusCode)),
+			t.m.errorType.Insert(statusValue(resp.StatusCode)))
+	default:
+		lvs = append(lvs, t.m.status.Insert(statusValue(resp.StatusCode)))
+	}
+	mctx, lerr := t.m.ms.ContextWithLabels(ctx, lvs...)
+	if lerr != nil {
+		log.Error("unable to install HTTP labels", lerr)
+	}
+
+	opentelemetry.SinceContext(mctx, t.m.duration, start)
+	if req.ContentLength >= 0 {
+		t.m.requestSize.RecordContext(mctx, float64(req.ContentLength))
+	}
+	if err != nil {
+		return resp, err
+	}
+	switch {
+	case resp.ContentLength >= 0:
+		t.m.responseSize.RecordContext(mctx, float64(resp.ContentLength))
+	case resp.StatusCode != http.StatusSwitchingProtocols:
+		// the size is only known once the body has been read, keep the
+		// writable body of switched protocols as is.
+		resp.Body = &responseBody{ReadCloser: resp.Body, record: func(n int64) {
+			t.m.responseSize.RecordContext(mctx, float64(n))
+		}}
+	}
+	return resp, nil
+}
+
+// portValue returns the port of the server, as recorded by the server.port
+// label.
+func portValue(req *http.Request) string {
+	if port := req.URL.Port(); port != "" {
+		return port
+	}
+	if req.URL.Scheme == "https" {
+		return "443"
+	}
+	return "80"
+}
+
+// responseBody records the number of bytes read from a response body once it
+// has been read to the end or closed.
+type responseBody struct {
+	io.ReadCloser
+	n      int64
+	once   sync.Once
+	record func(n int64)
+}
+
+func (b *responseBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	if err == io.EOF {
+		b.once.Do(func() { b.record(b.n) })
+	}
+	return n, err
+}
+
+func (b *responseBody) Close() error {
+	b.once.Do(func() { b.record(b.n) })
+	return b.ReadCloser.Close()
+}
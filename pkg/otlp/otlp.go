@@ -0,0 +1,242 @@
+// Copyright (c) Tetrate, Inc 2023.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package otlp provides an OTLP push exporter for metric sinks created by
+// opentelemetry.New.
+package otlp
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tetratelabs/telemetry"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	api "go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/sdk/metric"
+
+	opentelemetry "github.com/tetratelabs/telemetry-opentelemetry"
+)
+
+// Protocol is the transport used to ship metrics to an OTLP receiver.
+type Protocol string
+
+// Supported OTLP transports.
+const (
+	GRPC Protocol = "grpc"
+	HTTP Protocol = "http/protobuf"
+)
+
+// Option configures the OTLP exporter.
+type Option func(*config)
+
+type config struct {
+	protocol Protocol
+	endpoint string
+	urlPath  string
+	insecure bool
+	headers  map[string]string
+	interval time.Duration
+	timeout  time.Duration
+	mpOpts   []metric.Option
+}
+
+// WithProtocol sets the transport used to reach the receiver. Defaults to GRPC.
+func WithProtocol(p Protocol) Option {
+	return func(c *config) {
+		c.protocol = p
+	}
+}
+
+// WithEndpoint sets the host:port of the receiver. If not set, the endpoint is
+// read from the standard OTEL_EXPORTER_OTLP_* environment variables.
+func WithEndpoint(endpoint string) Option {
+	return func(c *config) {
+		c.endpoint = endpoint
+	}
+}
+
+// WithURLPath sets the URL path metrics are posted to. Only used by HTTP.
+func WithURLPath(path string) Option {
+	return func(c *config) {
+		c.urlPath = path
+	}
+}
+
+// WithInsecure disables transport security.
+func WithInsecure() Option {
+	return func(c *config) {
+		c.insecure = true
+	}
+}
+
+// WithHeaders sets additional headers sent with every export request.
+func WithHeaders(headers map[string]string) Option {
+	return func(c *config) {
+		c.headers = headers
+	}
+}
+
+// WithInterval sets the interval between two consecutive exports.
+func WithInterval(d time.Duration) Option {
+	return func(c *config) {
+		c.interval = d
+	}
+}
+
+// WithTimeout sets the maximum duration of a single export.
+func WithTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.timeout = d
+	}
+}
+
+// WithMeterProviderOptions sets additional options applied to the
+// MeterProvider created by NewExporter.
+func WithMeterProviderOptions(opts ...metric.Option) Option {
+	return func(c *config) {
+		c.mpOpts = append(c.mpOpts, opts...)
+	}
+}
+
+// NewReader returns a periodic metric.Reader pushing to an OTLP receiver
+// configured by the provided options.
+func NewReader(ctx context.Context, opts ...Option) (metric.Reader, error) {
+	c := newConfig(opts)
+
+	var (
+		exp metric.Exporter
+		err error
+	)
+	switch c.protocol {
+	case GRPC:
+		exp, err = otlpmetricgrpc.New(ctx, c.grpcOptions()...)
+	case HTTP:
+		exp, err = otlpmetrichttp.New(ctx, c.httpOptions()...)
+	default:
+		return nil, fmt.Errorf("unsupported OTLP protocol %q", c.protocol)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var readerOpts []metric.PeriodicReaderOption
+	if c.interval > 0 {
+		readerOpts = append(readerOpts, metric.WithInterval(c.interval))
+	}
+	if c.timeout > 0 {
+		readerOpts = append(readerOpts, metric.WithTimeout(c.timeout))
+	}
+	return metric.NewPeriodicReader(exp, readerOpts...), nil
+}
+
+// Exporter is the handle of a registered OTLP exporter, controlling its
+// lifetime.
+type Exporter struct {
+	mp *opentelemetry.MeterProvider
+}
+
+// ForceFlush pushes all pending metrics to the receiver.
+func (e *Exporter) ForceFlush(ctx context.Context) error {
+	return e.mp.ForceFlush(ctx)
+}
+
+// Shutdown pushes the final interval to the receiver and shuts down the
+// exporter.
+func (e *Exporter) Shutdown(ctx context.Context) error {
+	return e.mp.Shutdown(ctx)
+}
+
+// MeterProvider returns the MeterProvider collecting the metrics pushed by
+// the exporter.
+func (e *Exporter) MeterProvider() api.MeterProvider {
+	return e.mp
+}
+
+// RegisterOTLPExporter sets the global metrics handler to an OTLP exporter
+// configured by the provided options.
+// Returned is an Exporter, which must be shut down to not lose the final
+// interval.
+func RegisterOTLPExporter(
+	ctx context.Context, ms telemetry.MetricSink, opts ...Option,
+) (*Exporter, error) {
+	e, err := NewExporter(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	e.mp.Attach(ms)
+	otel.SetMeterProvider(e.mp)
+	return e, nil
+}
+
+// NewExporter returns an OTLP exporter configured by the provided options
+// without installing it globally. Sinks report to it when created with
+// opentelemetry.WithMeterProvider(e.MeterProvider()), which applies their
+// views, exemplar filter and resource.
+func NewExporter(ctx context.Context, opts ...Option) (*Exporter, error) {
+	reader, err := NewReader(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	c := newConfig(opts)
+	mp := opentelemetry.NewMeterProvider(append([]metric.Option{metric.WithReader(reader)}, c.mpOpts...)...)
+	return &Exporter{mp: mp}, nil
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{protocol: GRPC}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) grpcOptions() []otlpmetricgrpc.Option {
+	var opts []otlpmetricgrpc.Option
+	if c.endpoint != "" {
+		opts = append(opts, otlpmetricgrpc.WithEndpoint(c.endpoint))
+	}
+	if c.insecure {
+		opts = append(opts, otlpmetricgrpc.WithInsecure())
+	}
+	if len(c.headers) > 0 {
+		opts = append(opts, otlpmetricgrpc.WithHeaders(c.headers))
+	}
+	if c.timeout > 0 {
+		opts = append(opts, otlpmetricgrpc.WithTimeout(c.timeout))
+	}
+	return opts
+}
+
+func (c *config) httpOptions() []otlpmetrichttp.Option {
+	var opts []otlpmetrichttp.Option
+	if c.endpoint != "" {
+		opts = append(opts, otlpmetrichttp.WithEndpoint(c.endpoint))
+	}
+	if c.urlPath != "" {
+		opts = append(opts, otlpmetrichttp.WithURLPath(c.urlPath))
+	}
+	if c.insecure {
+		opts = append(opts, otlpmetrichttp.WithInsecure())
+	}
+	if len(c.headers) > 0 {
+		opts = append(opts, otlpmetrichttp.WithHeaders(c.headers))
+	}
+	if c.timeout > 0 {
+		opts = append(opts, otlpmetrichttp.WithTimeout(c.timeout))
+	}
+	return opts
+}
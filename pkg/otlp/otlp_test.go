@@ -0,0 +1,205 @@
+// Copyright (c) Tetrate, Inc 2023.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/tetratelabs/telemetry"
+	collectormetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+
+	opentelemetry "github.com/tetratelabs/telemetry-opentelemetry"
+	"github.com/tetratelabs/telemetry-opentelemetry/pkg/otlp"
+)
+
+// receiver is an in-process fake OTLP metrics receiver.
+type receiver struct {
+	collectormetrics.UnimplementedMetricsServiceServer
+
+	mu      sync.Mutex
+	metrics map[string]*metricspb.Metric
+}
+
+func newReceiver() *receiver {
+	return &receiver{metrics: map[string]*metricspb.Metric{}}
+}
+
+func (r *receiver) Export(
+	_ context.Context, req *collectormetrics.ExportMetricsServiceRequest,
+) (*collectormetrics.ExportMetricsServiceResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, rm := range req.ResourceMetrics {
+		for _, sm := range rm.ScopeMetrics {
+			for _, m := range sm.Metrics {
+				r.metrics[m.Name] = m
+			}
+		}
+	}
+	return &collectormetrics.ExportMetricsServiceResponse{}, nil
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var in collectormetrics.ExportMetricsServiceRequest
+	if err = proto.Unmarshal(body, &in); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	out, _ := r.Export(req.Context(), &in)
+	b, _ := proto.Marshal(out)
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	_, _ = w.Write(b)
+}
+
+func (r *receiver) metric(name string) *metricspb.Metric {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.metrics[name]
+}
+
+func TestRegisterOTLPExporterGRPC(t *testing.T) {
+	rcv := newReceiver()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	collectormetrics.RegisterMetricsServiceServer(srv, rcv)
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Stop()
+
+	ms := opentelemetry.New("otlp-grpc", opentelemetry.WithExemplarFilter(opentelemetry.ExemplarFilterAlways))
+	requests := ms.NewSum("requests_total", "Number of requests")
+	latency := ms.NewDistribution("latency", "Request latency", []float64{1, 2, 5})
+	size := ms.NewDistribution("size", "Response size", nil, opentelemetry.WithExponentialHistogram(20, 4))
+
+	exp, err := otlp.RegisterOTLPExporter(context.Background(), ms,
+		otlp.WithEndpoint(lis.Addr().String()),
+		otlp.WithInsecure(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requests.Increment()
+	if err = exp.ForceFlush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if rcv.metric("requests_total") == nil {
+		t.Fatal("requests_total was not flushed")
+	}
+
+	requests.Increment()
+	latency.Record(3)
+	size.Record(1024)
+
+	// shutdown pushes the final interval to the receiver.
+	if err = exp.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	sum := rcv.metric("requests_total")
+	if sum == nil {
+		t.Fatal("requests_total was not exported")
+	}
+	if got := sum.GetSum().GetDataPoints()[0].GetAsDouble(); got != 2 {
+		t.Errorf("want requests_total 2, got %v", got)
+	}
+	if len(sum.GetSum().GetDataPoints()[0].GetExemplars()) == 0 {
+		t.Error("want requests_total exemplars")
+	}
+	assertBounds(t, rcv.metric("latency"), []float64{1, 2, 5})
+
+	dps := rcv.metric("size").GetExponentialHistogram().GetDataPoints()
+	if len(dps) != 1 {
+		t.Fatalf("want 1 exponential histogram data point, got %d", len(dps))
+	}
+	if dps[0].GetScale() > 4 {
+		t.Errorf("want scale at most 4, got %d", dps[0].GetScale())
+	}
+}
+
+func TestNewExporterHTTP(t *testing.T) {
+	rcv := newReceiver()
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	exp, err := otlp.NewExporter(context.Background(),
+		otlp.WithProtocol(otlp.HTTP),
+		otlp.WithEndpoint(strings.TrimPrefix(srv.URL, "http://")),
+		otlp.WithInsecure(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms := opentelemetry.New("otlp-http", opentelemetry.WithMeterProvider(exp.MeterProvider()))
+	ms.NewDistribution("http_latency", "Request latency", []float64{10, 20}).Record(15)
+	ms.NewSum("http_received_bytes_total", "Received bytes", telemetry.WithUnit(telemetry.Bytes)).Record(1 << 60)
+	ms.NewDistribution("http_size", "Response size", nil, opentelemetry.WithExponentialHistogram(20, 4)).Record(1024)
+
+	if err = exp.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	assertBounds(t, rcv.metric("http_latency"), []float64{10, 20})
+	if dps := rcv.metric("http_size").GetExponentialHistogram().GetDataPoints(); len(dps) != 1 {
+		t.Fatalf("want 1 exponential histogram data point, got %d", len(dps))
+	}
+
+	dp := rcv.metric("http_received_bytes_total").GetSum().GetDataPoints()[0]
+	if _, ok := dp.GetValue().(*metricspb.NumberDataPoint_AsInt); !ok || dp.GetAsInt() != 1<<60 {
+		t.Errorf("want integer data point %d, got %v", int64(1<<60), dp.GetValue())
+	}
+}
+
+func TestNewReaderUnsupportedProtocol(t *testing.T) {
+	if _, err := otlp.NewReader(context.Background(), otlp.WithProtocol("thrift")); err == nil {
+		t.Fatal("expected error for unsupported protocol")
+	}
+}
+
+func assertBounds(t *testing.T, m *metricspb.Metric, want []float64) {
+	t.Helper()
+	if m == nil {
+		t.Fatal("histogram was not exported")
+	}
+	dps := m.GetHistogram().GetDataPoints()
+	if len(dps) != 1 {
+		t.Fatalf("want 1 histogram data point, got %d", len(dps))
+	}
+	got := dps[0].GetExplicitBounds()
+	if len(got) != len(want) {
+		t.Fatalf("want bounds %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("want bounds %v, got %v", want, got)
+		}
+	}
+}
@@ -13,51 +13,265 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
+// Package prometheus provides a Prometheus exporter for metric sinks created
+// by opentelemetry.New.
+//
+// Metric and label names, including the resource attributes of target_info,
+// are exported with the characters legacy Prometheus names do not allow, such
+// as dots or dashes, replaced by underscores, e.g. http_requests. Use
+// WithUTF8Names to export them as recorded instead: scrapers negotiating UTF-8
+// names then receive them unchanged, e.g. http.requests, while other scrapers
+// still receive them escaped.
 package prometheus
 
 import (
+	"context"
 	"net/http"
+	"sync"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/model"
 	"github.com/tetratelabs/telemetry"
 	"go.opentelemetry.io/otel"
 	otelprom "go.opentelemetry.io/otel/exporters/prometheus"
+	api "go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/sdk/metric"
 
 	opentelemetry "github.com/tetratelabs/telemetry-opentelemetry"
 )
 
+// Exporter is the handle of a registered Prometheus exporter. It serves the
+// gathered metrics over HTTP and controls the lifetime of the exporter.
+type Exporter struct {
+	http.Handler
+
+	mp  *opentelemetry.MeterProvider
+	reg *registerer
+}
+
+// ForceFlush flushes all pending telemetry of the exporter.
+func (e *Exporter) ForceFlush(ctx context.Context) error {
+	return e.mp.ForceFlush(ctx)
+}
+
+// Shutdown shuts down the exporter and unregisters its collectors from the
+// Prometheus registerer. After Shutdown, metrics are no longer collected.
+func (e *Exporter) Shutdown(ctx context.Context) error {
+	err := e.mp.Shutdown(ctx)
+	e.reg.unregisterAll()
+	return err
+}
+
+// MeterProvider returns the MeterProvider collecting the metrics exported by
+// the exporter.
+func (e *Exporter) MeterProvider() api.MeterProvider {
+	return e.mp
+}
+
+// Option configures the Prometheus exporter.
+type Option func(*config)
+
+type config struct {
+	targetInfo  bool
+	scopeInfo   bool
+	openMetrics bool
+	utf8Names   bool
+	mpOpts      []metric.Option
+}
+
+// WithTargetInfo enables the target_info metric, exposing the attributes of
+// the Resource configured for the sink.
+func WithTargetInfo() Option {
+	return func(c *config) {
+		c.targetInfo = true
+	}
+}
+
+// WithScopeInfo enables the otel_scope_* labels, identifying the sink which
+// recorded the metric.
+func WithScopeInfo() Option {
+	return func(c *config) {
+		c.scopeInfo = true
+	}
+}
+
+// WithOpenMetrics serves the OpenMetrics exposition format to scrapers
+// requesting it, which is required to expose exemplars. Note that counters
+// created without the _total suffix are exposed as unknown in that format.
+func WithOpenMetrics() Option {
+	return func(c *config) {
+		c.openMetrics = true
+	}
+}
+
+// WithUTF8Names exports metric and label names as recorded, rather than with
+// the characters legacy Prometheus names do not allow replaced by underscores.
+// Scrapers which do not negotiate UTF-8 names keep receiving escaped names.
+func WithUTF8Names() Option {
+	return func(c *config) {
+		c.utf8Names = true
+	}
+}
+
+// WithMeterProviderOptions sets additional options applied to the
+// MeterProvider of the exporter, e.g. metric.WithResource.
+func WithMeterProviderOptions(opts ...metric.Option) Option {
+	return func(c *config) {
+		c.mpOpts = append(c.mpOpts, opts...)
+	}
+}
+
 // RegisterPrometheusExporter sets the global metrics handler to the provided
 // Prometheus registerer and gatherer.
-// Returned is an HTTP handler that can be used to read metrics from.
+// Returned is an Exporter, which is an HTTP handler that can be used to read
+// metrics from.
 func RegisterPrometheusExporter(
-	ms telemetry.MetricSink, reg prometheus.Registerer, gat prometheus.Gatherer,
-) (http.Handler, error) {
+	ms telemetry.MetricSink, reg prometheus.Registerer, gat prometheus.Gatherer, opts ...Option,
+) (*Exporter, error) {
+	e, err := NewPrometheusExporter(reg, gat, opts...)
+	if err != nil {
+		return nil, err
+	}
+	e.mp.Attach(ms)
+	otel.SetMeterProvider(e.mp)
+	return e, nil
+}
+
+// NewPrometheusExporter returns an Exporter for the provided Prometheus
+// registerer and gatherer without installing it globally. Sinks report to it
+// when created with opentelemetry.WithMeterProvider(e.MeterProvider()), which
+// applies their views, exemplar filter and resource.
+func NewPrometheusExporter(
+	reg prometheus.Registerer, gat prometheus.Gatherer, opts ...Option,
+) (*Exporter, error) {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
 	if reg == nil {
 		reg = prometheus.DefaultRegisterer
 	}
 	if gat == nil {
 		gat = prometheus.DefaultGatherer
 	}
+	r := &registerer{Registerer: reg}
+	var promReg prometheus.Registerer = r
+	if !c.utf8Names {
+		// the exporter collects into a private registry, whose metrics are
+		// escaped when collected from reg.
+		private := prometheus.NewRegistry()
+		if err := r.Register(escapingCollector{private}); err != nil {
+			return nil, err
+		}
+		promReg = private
+	}
 	promOpts := []otelprom.Option{
-		otelprom.WithoutScopeInfo(),
-		otelprom.WithoutTargetInfo(),
 		otelprom.WithoutUnits(),
-		otelprom.WithRegisterer(reg),
+		otelprom.WithRegisterer(promReg),
 		otelprom.WithoutCounterSuffixes(),
 	}
+	if !c.scopeInfo {
+		promOpts = append(promOpts, otelprom.WithoutScopeInfo())
+	}
+	if !c.targetInfo {
+		promOpts = append(promOpts, otelprom.WithoutTargetInfo())
+	}
 
 	prom, err := otelprom.New(promOpts...)
 	if err != nil {
+		r.unregisterAll()
 		return nil, err
 	}
 
-	opts := []metric.Option{metric.WithReader(prom)}
-	opts = append(opts, opentelemetry.Start(ms)...)
+	mp := opentelemetry.NewMeterProvider(append([]metric.Option{metric.WithReader(prom)}, c.mpOpts...)...)
+	handler := promhttp.HandlerFor(gat, promhttp.HandlerOpts{EnableOpenMetrics: c.openMetrics})
+	return &Exporter{Handler: handler, mp: mp, reg: r}, nil
+}
+
+// registerer records the collectors registered through it, so they can be
+// unregistered when the exporter shuts down.
+type registerer struct {
+	prometheus.Registerer
+
+	mu         sync.Mutex
+	collectors []prometheus.Collector
+}
+
+func (r *registerer) Register(c prometheus.Collector) error {
+	if err := r.Registerer.Register(c); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.collectors = append(r.collectors, c)
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *registerer) MustRegister(cs ...prometheus.Collector) {
+	for _, c := range cs {
+		if err := r.Register(c); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func (r *registerer) unregisterAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range r.collectors {
+		r.Registerer.Unregister(c)
+	}
+	r.collectors = nil
+}
+
+// escapingCollector collects the metrics gathered by a Gatherer, with the
+// characters legacy Prometheus names do not allow replaced by underscores in
+// their metric and label names.
+type escapingCollector struct {
+	gat prometheus.Gatherer
+}
+
+// Describe implements prometheus.Collector. Like the OpenTelemetry collector
+// it wraps, the collector is unchecked.
+func (escapingCollector) Describe(chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector.
+func (c escapingCollector) Collect(ch chan<- prometheus.Metric) {
+	mfs, err := c.gat.Gather()
+	if err != nil {
+		otel.Handle(err)
+	}
+	for _, mf := range mfs {
+		mf = model.EscapeMetricFamily(mf, model.UnderscoreEscaping)
+		for _, m := range mf.Metric {
+			names := make([]string, 0, len(m.Label))
+			for _, l := range m.Label {
+				names = append(names, l.GetName())
+			}
+			ch <- escapedMetric{prometheus.NewDesc(mf.GetName(), mf.GetHelp(), names, nil), m}
+		}
+	}
+}
+
+// escapedMetric is a gathered metric collected again under an escaped name.
+type escapedMetric struct {
+	desc *prometheus.Desc
+	m    *dto.Metric
+}
+
+func (e escapedMetric) Desc() *prometheus.Desc {
+	return e.desc
+}
 
-	mp := metric.NewMeterProvider(opts...)
-	otel.SetMeterProvider(mp)
-	handler := promhttp.HandlerFor(gat, promhttp.HandlerOpts{})
-	return handler, nil
+func (e escapedMetric) Write(out *dto.Metric) error {
+	out.Label = e.m.Label
+	out.Gauge = e.m.Gauge
+	out.Counter = e.m.Counter
+	out.Summary = e.m.Summary
+	out.Untyped = e.m.Untyped
+	out.Histogram = e.m.Histogram
+	out.TimestampMs = e.m.TimestampMs
+	return nil
 }
@@ -0,0 +1,275 @@
+// Copyright (c) Tetrate, Inc 2023.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/trace"
+
+	opentelemetry "github.com/tetratelabs/telemetry-opentelemetry"
+	otelprom "github.com/tetratelabs/telemetry-opentelemetry/pkg/prometheus"
+)
+
+func TestExporterShutdown(t *testing.T) {
+	ms := opentelemetry.New("prometheus-shutdown")
+	requests := ms.NewSum("requests_total", "Number of requests")
+
+	reg := prometheus.NewRegistry()
+	exp, err := otelprom.RegisterPrometheusExporter(ms, reg, reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requests.Increment()
+	if err = exp.ForceFlush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if mfs, _ := reg.Gather(); len(mfs) == 0 {
+		t.Fatal("want metrics before shutdown")
+	}
+
+	if err = exp.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if mfs, _ := reg.Gather(); len(mfs) != 0 {
+		t.Fatalf("want no metrics after shutdown, got %d", len(mfs))
+	}
+
+	// the registerer is free to be used by a new exporter.
+	exp, err = otelprom.RegisterPrometheusExporter(ms, reg, reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = exp.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIsolatedExporters(t *testing.T) {
+	regA, regB := prometheus.NewRegistry(), prometheus.NewRegistry()
+	expA, err := otelprom.NewPrometheusExporter(regA, regA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expB, err := otelprom.NewPrometheusExporter(regB, regB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sinkA := opentelemetry.New("a", opentelemetry.WithMeterProvider(expA.MeterProvider()))
+	sinkB := opentelemetry.New("b", opentelemetry.WithMeterProvider(expB.MeterProvider()))
+	sinkA.NewSum("jobs_total", "Number of jobs").Record(3)
+	sinkB.NewSum("jobs_total", "Number of jobs").Record(5)
+
+	assertCounter(t, regA, "jobs_total", 3)
+	assertCounter(t, regB, "jobs_total", 5)
+}
+
+func assertCounter(t *testing.T, gat prometheus.Gatherer, name string, want float64) {
+	t.Helper()
+	mfs, err := gat.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mf := range mfs {
+		if mf.GetName() != name {
+			continue
+		}
+		if got := mf.Metric[0].GetCounter().GetValue(); got != want {
+			t.Fatalf("want %s %v, got %v", name, want, got)
+		}
+		return
+	}
+	t.Fatalf("metric %s not found", name)
+}
+
+func TestTargetAndScopeInfo(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	exp, err := otelprom.NewPrometheusExporter(reg, reg,
+		otelprom.WithTargetInfo(),
+		otelprom.WithScopeInfo(),
+		otelprom.WithMeterProviderOptions(metric.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", "svc"),
+		))),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms := opentelemetry.New("scoped", opentelemetry.WithMeterProvider(exp.MeterProvider()))
+	ms.NewSum("jobs_total", "Number of jobs").Increment()
+
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][2]string{
+		"target_info": {"service_name", "svc"},
+		"jobs_total":  {"otel_scope_name", "scoped"},
+	}
+	for _, mf := range mfs {
+		label, ok := want[mf.GetName()]
+		if !ok {
+			continue
+		}
+		for _, l := range mf.Metric[0].Label {
+			if l.GetName() == label[0] && l.GetValue() == label[1] {
+				delete(want, mf.GetName())
+			}
+		}
+	}
+	if len(want) > 0 {
+		t.Fatalf("missing labels: %v", want)
+	}
+}
+
+func TestSinkConfiguration(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	exp, err := otelprom.NewPrometheusExporter(reg, reg, otelprom.WithTargetInfo())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms := opentelemetry.New("configured",
+		opentelemetry.WithMeterProvider(exp.MeterProvider()),
+		opentelemetry.WithResource(resource.NewSchemaless(attribute.String("service.version", "1.2.3"))),
+		opentelemetry.WithExemplarFilter(opentelemetry.ExemplarFilterNever),
+	)
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x01},
+		SpanID:     trace.SpanID{0x02},
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), sc)
+	ms.NewSum("jobs_total", "Number of jobs").RecordContext(ctx, 1)
+	ms.NewDistribution("job_size", "Job size", nil,
+		opentelemetry.WithExponentialHistogram(20, 4)).Record(1024)
+
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := map[string]bool{}
+	for _, mf := range mfs {
+		m := mf.Metric[0]
+		switch mf.GetName() {
+		case "target_info":
+			for _, l := range m.Label {
+				if l.GetName() == "service_name" && l.GetValue() != "configured" ||
+					l.GetName() == "service_version" && l.GetValue() != "1.2.3" {
+					t.Errorf("unexpected target_info label %s=%s", l.GetName(), l.GetValue())
+				}
+			}
+		case "jobs_total":
+			if m.GetCounter().GetExemplar() != nil {
+				t.Error("want no exemplar with ExemplarFilterNever")
+			}
+		case "job_size":
+			if h := m.GetHistogram(); h.Schema == nil || h.GetSchema() > 4 {
+				t.Errorf("want a native histogram of schema at most 4, got %v", h)
+			}
+		default:
+			continue
+		}
+		found[mf.GetName()] = true
+	}
+	if len(found) != 3 {
+		t.Fatalf("want target_info, jobs_total and job_size, got %v", found)
+	}
+}
+
+func TestOpenMetrics(t *testing.T) {
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x01},
+		SpanID:     trace.SpanID{0x02},
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), sc)
+
+	tests := []struct {
+		name        string
+		opts        []otelprom.Option
+		contentType string
+		exemplar    bool
+	}{
+		{"default", nil, "text/plain", false},
+		{"enabled", []otelprom.Option{otelprom.WithOpenMetrics()}, "application/openmetrics-text", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := prometheus.NewRegistry()
+			exp, err := otelprom.NewPrometheusExporter(reg, reg, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ms := opentelemetry.New("openmetrics", opentelemetry.WithMeterProvider(exp.MeterProvider()))
+			ms.NewSum("jobs_total", "Number of jobs").RecordContext(ctx, 1)
+
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			req.Header.Set("Accept", "application/openmetrics-text; version=1.0.0")
+			rec := httptest.NewRecorder()
+			exp.ServeHTTP(rec, req)
+			if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, tt.contentType) {
+				t.Errorf("want content type %s, got %s", tt.contentType, got)
+			}
+			if got := strings.Contains(rec.Body.String(), "trace_id"); got != tt.exemplar {
+				t.Errorf("want exemplar %v, got:\n%s", tt.exemplar, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestMetricNames(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []otelprom.Option
+		accept string
+		want   string
+	}{
+		{"legacy", nil, "text/plain; version=0.0.4", `http_requests{http_method="GET"} 1`},
+		{"legacy utf-8 scraper", nil, "text/plain; version=1.0.0; escaping=allow-utf-8", `http_requests{http_method="GET"} 1`},
+		{"utf-8 names", []otelprom.Option{otelprom.WithUTF8Names()},
+			"text/plain; version=0.0.4", `http_requests{http_method="GET"} 1`},
+		{"utf-8 names utf-8 scraper", []otelprom.Option{otelprom.WithUTF8Names()},
+			"text/plain; version=1.0.0; escaping=allow-utf-8", `{"http.requests","http.method"="GET"} 1`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := prometheus.NewRegistry()
+			exp, err := otelprom.NewPrometheusExporter(reg, reg, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ms := opentelemetry.New("names", opentelemetry.WithMeterProvider(exp.MeterProvider()))
+			method := ms.NewLabel("http.method")
+			ms.NewSum("http.requests", "Number of requests").With(method.Insert("GET")).Increment()
+
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			req.Header.Set("Accept", tt.accept)
+			rec := httptest.NewRecorder()
+			exp.ServeHTTP(rec, req)
+			if body := rec.Body.String(); !strings.Contains(body, tt.want) {
+				t.Errorf("want %q in exposition, got:\n%s", tt.want, body)
+			}
+		})
+	}
+}
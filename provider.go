@@ -0,0 +1,152 @@
+// Copyright (c) Tetrate, Inc 2023.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opentelemetry
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/tetratelabs/telemetry"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/embedded"
+	sdk "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// errConflictingSink is logged when a sink attached to a MeterProvider sets an
+// exemplar filter or resource other than the one the MeterProvider applies.
+var errConflictingSink = errors.New("sink configuration conflicts with the MeterProvider")
+
+// MeterProvider is the MeterProvider of the exporters which are not installed
+// globally. Sinks created with WithMeterProvider using it are attached to it,
+// so it applies their histogram views, exemplar filter and resource like the
+// options returned by Start.
+//
+// The exemplar filter and resource apply to all metrics of the MeterProvider,
+// so they are taken from the first attached sink setting them. The underlying
+// SDK MeterProvider is created when the first Meter is requested, i.e. when the
+// first sink is created with it; sinks attached later keep applying their
+// views to their own metrics. Attaching a sink whose exemplar filter or
+// resource differs from the applied one, or sets one once the SDK
+// MeterProvider has been created without it, is reported through the logger
+// of the sink.
+type MeterProvider struct {
+	embedded.MeterProvider
+
+	opts []sdk.Option
+
+	once sync.Once
+	mp   *sdk.MeterProvider
+
+	mu    sync.RWMutex
+	sinks []*metricSink
+	// filter and res are the settings applied to all metrics, and built is
+	// set once they can no longer change.
+	filter ExemplarFilter
+	res    *resource.Resource
+	built  bool
+}
+
+// NewMeterProvider returns a MeterProvider configured by the provided options,
+// which take precedence over the configuration of the attached sinks.
+func NewMeterProvider(opts ...sdk.Option) *MeterProvider {
+	return &MeterProvider{opts: opts}
+}
+
+// Attach applies the configuration of the sink to the MeterProvider. Sinks
+// created with WithMeterProvider using the MeterProvider are attached by New;
+// use Attach for sinks installed otherwise, e.g. through the global
+// MeterProvider.
+func (p *MeterProvider) Attach(ms telemetry.MetricSink) {
+	m, ok := ms.(*metricSink)
+	if !ok {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, s := range p.sinks {
+		if s.knownMetrics == m.knownMetrics {
+			return
+		}
+	}
+	p.sinks = append(p.sinks, m)
+
+	if f := m.exemplarFilter; f != ExemplarFilterDefault {
+		switch {
+		case p.filter == ExemplarFilterDefault && !p.built:
+			p.filter = f
+		case f != p.filter:
+			m.logger.Error("exemplar filter of the sink not applied", errConflictingSink, "sink", m.appName)
+		}
+	}
+	if res := m.toResource(); res != nil {
+		switch {
+		case p.res == nil && !p.built:
+			p.res = res
+		case !res.Equal(p.res):
+			m.logger.Error("resource of the sink not applied", errConflictingSink, "sink", m.appName)
+		}
+	}
+}
+
+// Meter implements metric.MeterProvider.
+func (p *MeterProvider) Meter(name string, opts ...metric.MeterOption) metric.Meter {
+	return p.provider().Meter(name, opts...)
+}
+
+// ForceFlush flushes all pending telemetry of the MeterProvider.
+func (p *MeterProvider) ForceFlush(ctx context.Context) error {
+	return p.provider().ForceFlush(ctx)
+}
+
+// Shutdown shuts down the MeterProvider.
+func (p *MeterProvider) Shutdown(ctx context.Context) error {
+	return p.provider().Shutdown(ctx)
+}
+
+// provider returns the SDK MeterProvider, creating it on first use.
+func (p *MeterProvider) provider() *sdk.MeterProvider {
+	p.once.Do(func() {
+		opts := []sdk.Option{sdk.WithView(p.view)}
+		p.mu.Lock()
+		p.built = true
+		if f := p.filter.toSDK(); f != nil {
+			opts = append(opts, sdk.WithExemplarFilter(f))
+		}
+		if p.res != nil {
+			opts = append(opts, sdk.WithResource(p.res))
+		}
+		p.mu.Unlock()
+		p.mp = sdk.NewMeterProvider(append(opts, p.opts...)...)
+	})
+	return p.mp
+}
+
+// view looks up the stream of the instrument in the definitions of the sink
+// which created it.
+func (p *MeterProvider) view(inst sdk.Instrument) (sdk.Stream, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for _, m := range p.sinks {
+		if m.appName != inst.Scope.Name {
+			continue
+		}
+		if s, ok := m.knownMetrics.view(inst); ok {
+			return s, true
+		}
+	}
+	return sdk.Stream{}, false
+}
@@ -0,0 +1,66 @@
+// Copyright (c) Tetrate, Inc 2023.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opentelemetry
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// WithResource sets the Resource describing the entity producing the metrics,
+// e.g. service.name, service.version, k8s.pod.name or host.name. If the
+// Resource holds no service.name, the appName provided to New is used. The
+// Resource is applied by exporters through Start.
+func WithResource(res *resource.Resource) SinkOption {
+	return func(ms *metricSink) {
+		ms.resource = res
+	}
+}
+
+// WithResourceFromEnv adds the attributes found in the OTEL_RESOURCE_ATTRIBUTES
+// and OTEL_SERVICE_NAME environment variables to the Resource of the sink.
+// They take precedence over the attributes set with WithResource.
+func WithResourceFromEnv() SinkOption {
+	return func(ms *metricSink) {
+		ms.resourceFromEnv = true
+	}
+}
+
+// toResource returns the Resource of the sink, or nil if none was configured.
+func (m *metricSink) toResource() *resource.Resource {
+	if m.resource == nil && !m.resourceFromEnv {
+		return nil
+	}
+	res := resource.NewSchemaless(attribute.String("service.name", m.appName))
+	for _, r := range []*resource.Resource{m.resource, m.envResource()} {
+		if r == nil {
+			continue
+		}
+		merged, err := resource.Merge(res, r)
+		if err != nil {
+			m.logger.Error("unable to merge resource", err)
+			continue
+		}
+		res = merged
+	}
+	return res
+}
+
+func (m *metricSink) envResource() *resource.Resource {
+	if !m.resourceFromEnv {
+		return nil
+	}
+	return resource.Environment()
+}
@@ -0,0 +1,110 @@
+// Copyright (c) Tetrate, Inc 2023.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opentelemetry
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/tetratelabs/telemetry"
+)
+
+// Units of time recognized by Timer and Since, in addition to
+// telemetry.Seconds and telemetry.Milliseconds.
+const (
+	Nanoseconds  telemetry.Unit = "ns"
+	Microseconds telemetry.Unit = "us"
+	Minutes      telemetry.Unit = "min"
+	Hours        telemetry.Unit = "h"
+)
+
+// errCoarseDurationUnit is logged when an integer Distribution is declared
+// with a unit of time which truncates shorter durations to 0.
+var errCoarseDurationUnit = errors.New("unit of time too coarse for integer values")
+
+// Timer measures the time elapsed since it was started, and records it to a
+// Distribution in the unit the Distribution was declared with.
+type Timer struct {
+	m     telemetry.Metric
+	start time.Time
+}
+
+// NewTimer starts a Timer recording to m, which is typically a Distribution
+// created with telemetry.WithUnit(telemetry.Seconds) or
+// telemetry.WithUnit(telemetry.Milliseconds). Integer Distributions truncate
+// the elapsed time, so they should use Milliseconds or a finer unit.
+func NewTimer(m telemetry.Metric) Timer {
+	return Timer{m: m, start: time.Now()}
+}
+
+// Stop records the time elapsed since the Timer was started and returns it.
+func (t Timer) Stop() time.Duration {
+	return SinceContext(context.Background(), t.m, t.start)
+}
+
+// StopContext records the time elapsed since the Timer was started, taking the
+// label values found in ctx into account, and returns it.
+func (t Timer) StopContext(ctx context.Context) time.Duration {
+	return SinceContext(ctx, t.m, t.start)
+}
+
+// Since records the time elapsed since start to m and returns it.
+func Since(m telemetry.Metric, start time.Time) time.Duration {
+	return SinceContext(context.Background(), m, start)
+}
+
+// SinceContext records the time elapsed since start to m, taking the label
+// values found in ctx into account, and returns it.
+// The elapsed time is converted to the unit m was declared with. Metrics
+// without a unit of time, or not created by this package, record seconds.
+func SinceContext(ctx context.Context, m telemetry.Metric, start time.Time) time.Duration {
+	d := time.Since(start)
+	var unit telemetry.Unit
+	if u, ok := m.(interface{ durationUnit() telemetry.Unit }); ok {
+		unit = u.durationUnit()
+	}
+	m.RecordContext(ctx, inUnit(d, unit))
+	return d
+}
+
+// inUnit returns d in the provided unit of time, falling back to seconds.
+func inUnit(d time.Duration, unit telemetry.Unit) float64 {
+	switch unit {
+	case Nanoseconds:
+		return float64(d.Nanoseconds())
+	case Microseconds:
+		return float64(d) / float64(time.Microsecond)
+	case telemetry.Milliseconds:
+		return float64(d) / float64(time.Millisecond)
+	case Minutes:
+		return d.Minutes()
+	case Hours:
+		return d.Hours()
+	default:
+		return d.Seconds()
+	}
+}
+
+// isCoarseDurationUnit reports whether integer values in unit truncate
+// sub-second durations.
+func isCoarseDurationUnit(unit telemetry.Unit) bool {
+	switch unit {
+	case telemetry.Seconds, Minutes, Hours:
+		return true
+	default:
+		return false
+	}
+}
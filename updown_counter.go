@@ -0,0 +1,124 @@
+// Copyright (c) Tetrate, Inc 2023.
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opentelemetry
+
+import (
+	"context"
+
+	"github.com/tetratelabs/telemetry"
+	api "go.opentelemetry.io/otel/metric"
+
+	"github.com/tetratelabs/telemetry-opentelemetry/internal/tag"
+)
+
+type upDownCounter struct {
+	baseMetric
+	c adder
+	// opts records with the bound label set
+	opts []api.AddOption
+}
+
+var _ telemetry.Metric = (*upDownCounter)(nil)
+
+func (m *metricSink) newUpDownCounter(name, description string, o metricOptions) *upDownCounter {
+	var (
+		c   adder
+		err error
+	)
+	if o.isInt64() {
+		var ic api.Int64UpDownCounter
+		ic, err = m.meter.Int64UpDownCounter(name,
+			api.WithDescription(description),
+			api.WithUnit(string(o.Unit)))
+		c = int64Adder{c: ic}
+	} else {
+		c, err = m.meter.Float64UpDownCounter(name,
+			api.WithDescription(description),
+			api.WithUnit(string(o.Unit)))
+	}
+	if err != nil {
+		log.Error("failed to create up/down counter", err)
+	}
+	r := &upDownCounter{c: c}
+	keysMap := map[tag.Key]bool{}
+	for _, k := range o.Labels {
+		if l, ok := k.(*labelImpl); ok {
+			keysMap[l.label] = true
+		}
+	}
+	r.baseMetric = baseMetric{
+		ms:      m,
+		name:    name,
+		rest:    r,
+		keys:    keysMap,
+		limiter: m.newCardinalityLimiter(name, o.cardinalityLimit),
+		enabled: o.enabled,
+		cache:   newLabelCache(m.labelCacheSize),
+	}
+	return r
+}
+
+func (f *upDownCounter) Record(value float64) {
+	f.RecordContext(context.Background(), value)
+}
+
+func (f *upDownCounter) RecordContext(ctx context.Context, value float64) {
+	if !f.enabled.Load() {
+		return
+	}
+	set := f.baseMetric.toLabelValues(ctx)
+	if set.Len() > 0 {
+		if as := f.limiter.admit(set); f.opts != nil && as.Equals(&f.set) {
+			// recording with the bound label set, reuse its options.
+			f.c.Add(ctx, value, f.opts...)
+		} else {
+			f.c.Add(ctx, value, api.WithAttributeSet(as))
+		}
+	} else {
+		f.c.Add(ctx, value)
+	}
+	f.ms.recordHooks.onRecord(f.name, set, value)
+}
+
+func (f *upDownCounter) With(labelValues ...telemetry.LabelValue) telemetry.Metric {
+	key, cached, cacheable := f.cache.lookup(labelValues)
+	if cached != nil {
+		return cached
+	}
+	nm := &upDownCounter{
+		c: f.c,
+	}
+	keysMap := make(map[tag.Key]bool)
+	for k := range f.keys {
+		keysMap[k] = true
+	}
+	nm.baseMetric = baseMetric{
+		ms:      f.ms,
+		name:    f.name,
+		rest:    nm,
+		keys:    keysMap,
+		limiter: f.limiter,
+		enabled: f.enabled,
+	}
+	nm.lvs, nm.set = f.baseMetric.withLabelValues(labelValues...)
+	if nm.set.Len() > 0 {
+		nm.opts = []api.AddOption{api.WithAttributeSet(nm.set)}
+	}
+	if cacheable {
+		f.cache.store(key, nm)
+	}
+	return nm
+}
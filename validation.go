@@ -0,0 +1,151 @@
+// Copyright (c) Tetrate, Inc 2023.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opentelemetry
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tetratelabs/telemetry-opentelemetry/internal/slices"
+)
+
+// NameValidation decides how metric and label names which are not valid for
+// both Prometheus and OpenTelemetry are handled.
+// Valid metric names start with a letter followed by letters, digits and
+// underscores, up to 255 characters. Valid label names start with a letter or
+// a single underscore followed by letters, digits and underscores.
+type NameValidation int
+
+const (
+	// NameValidationLenient accepts all names as provided.
+	NameValidationLenient NameValidation = iota
+	// NameValidationStrict rejects invalid names. Rejected metrics and labels
+	// discard their recordings, and conflicting re-registrations are rejected
+	// as well.
+	NameValidationStrict
+	// NameValidationSanitize replaces invalid characters with underscores.
+	// Names which cannot be fixed this way, such as names starting with a
+	// digit, are rejected.
+	NameValidationSanitize
+)
+
+const maxNameLength = 255
+
+var (
+	errInvalidMetricName = errors.New("invalid metric name")
+	errInvalidLabelName  = errors.New("invalid label name")
+)
+
+// WithNameValidation sets how the sink handles invalid metric and label names.
+// Errors are reported through the sink logger. Defaults to
+// NameValidationLenient.
+func WithNameValidation(v NameValidation) SinkOption {
+	return func(ms *metricSink) {
+		ms.nameValidation = v
+	}
+}
+
+// validateMetricName returns the name to use for the metric, and false if the
+// metric is to be rejected.
+func (m *metricSink) validateMetricName(name string) (string, bool) {
+	return m.validate(name, isValidMetricName, errInvalidMetricName, "metric")
+}
+
+// validateLabelName returns the name to use for the label, and false if the
+// label is to be rejected.
+func (m *metricSink) validateLabelName(name string) (string, bool) {
+	return m.validate(name, isValidLabelName, errInvalidLabelName, "label")
+}
+
+func (m *metricSink) validate(
+	name string, valid func(string) bool, errInvalid error, kind string,
+) (string, bool) {
+	if m.nameValidation == NameValidationLenient || valid(name) {
+		return name, true
+	}
+	if m.nameValidation == NameValidationSanitize {
+		if sanitized := sanitizeName(name); valid(sanitized) {
+			m.logger.Debug("sanitized "+kind+" name", kind, name, "name", sanitized)
+			return sanitized, true
+		}
+	}
+	m.logger.Error("rejected "+kind, errInvalid, kind, name)
+	return name, false
+}
+
+func isValidMetricName(name string) bool {
+	if name == "" || len(name) > maxNameLength || !isLetter(name[0]) {
+		return false
+	}
+	return isNameTail(name[1:])
+}
+
+func isValidLabelName(name string) bool {
+	if name == "" || len(name) > maxNameLength || strings.HasPrefix(name, "__") {
+		// label names starting with __ are reserved by Prometheus.
+		return false
+	}
+	if !isLetter(name[0]) && name[0] != '_' {
+		return false
+	}
+	return isNameTail(name[1:])
+}
+
+func isNameTail(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isNameChar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNameChar(c byte) bool {
+	return isLetter(c) || (c >= '0' && c <= '9') || c == '_'
+}
+
+// sanitizeName replaces each character which is not allowed in names with an
+// underscore.
+func sanitizeName(name string) string {
+	var b strings.Builder
+	b.Grow(len(name))
+	for _, r := range name {
+		if r < 0x80 && isNameChar(byte(r)) {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// conflict describes how def conflicts with the previous registration of a
+// metric with the same name, or returns nil if it does not.
+func (def MetricDefinition) conflict(prev MetricDefinition) error {
+	if def.Type != prev.Type {
+		return fmt.Errorf("metric %q registered as %s, previously registered as %s", def.Name, def.Type, prev.Type)
+	}
+	if def.Aggregation != prev.Aggregation ||
+		!slices.Equal(def.Bounds, prev.Bounds) ||
+		(def.exponential != nil && *def.exponential != *prev.exponential) {
+		return fmt.Errorf("distribution %q registered with a different aggregation or bounds", def.Name)
+	}
+	return nil
+}